@@ -0,0 +1,52 @@
+package main
+
+import (
+	"time"
+
+	"ai-gatway/pkg/logger"
+	"ai-gatway/pkg/utils"
+)
+
+// defaultJWTSecret 示例配置中的JWT密钥，生产环境必须替换
+const defaultJWTSecret = "change-this-in-production"
+
+// checkConfig 校验认证服务配置，不绑定端口
+func checkConfig() (report *utils.ConfigReport) {
+	report = &utils.ConfigReport{}
+	if !utils.CheckConfigFile(report) {
+		return report
+	}
+
+	port, logLevel, jwtSecret, tokenExpiry := utils.GetAuthConfig()
+	if port <= 0 || port > 65535 {
+		report.Errorf("auth.port %d is not a valid port", port)
+	} else {
+		report.Passf("auth.port %d", port)
+	}
+
+	switch {
+	case jwtSecret == "":
+		report.Errorf("auth.jwt_secret must be set")
+	case jwtSecret == defaultJWTSecret:
+		report.Warnf("auth.jwt_secret is still the example value")
+	case len(jwtSecret) < 32:
+		report.Warnf("auth.jwt_secret is shorter than 32 characters")
+	default:
+		report.Passf("auth.jwt_secret set")
+	}
+
+	if utils.CheckDuration(report, "auth.token_expiry", time.Second) {
+		if tokenExpiry <= 0 {
+			report.Errorf("auth.token_expiry must be a positive duration (seconds or e.g. \"24h\")")
+		} else {
+			report.Passf("auth.token_expiry %s", utils.FormatDurationShort(tokenExpiry))
+		}
+	}
+
+	_, logFormat, logFile := utils.GetLogConfig("auth")
+	if err := (logger.Config{Level: logLevel, Format: logFormat, File: logFile}).Validate(); err != nil {
+		report.Errorf("auth logging: %v", err)
+	}
+
+	return report
+}
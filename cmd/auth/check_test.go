@@ -0,0 +1,82 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"ai-gatway/pkg/utils"
+)
+
+// useConfig 将配置写入临时文件并加载
+func useConfig(t *testing.T, content string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := utils.SetConfigFile(path); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCheckConfig(t *testing.T) {
+	tests := []struct {
+		name        string
+		config      string
+		wantError   string
+		wantWarning string
+	}{
+		{
+			name:   "valid",
+			config: "auth:\n  port: 8082\n  jwt_secret: \"0123456789abcdef0123456789abcdef\"\n  token_expiry: 3600\n",
+		},
+		{
+			name:      "missing secret",
+			config:    "auth:\n  port: 8082\n  token_expiry: 3600\n",
+			wantError: "auth.jwt_secret must be set",
+		},
+		{
+			name:        "example secret",
+			config:      "auth:\n  port: 8082\n  jwt_secret: \"change-this-in-production\"\n  token_expiry: 3600\n",
+			wantWarning: "example value",
+		},
+		{
+			name:      "non-positive expiry",
+			config:    "auth:\n  port: 8082\n  jwt_secret: \"0123456789abcdef0123456789abcdef\"\n  token_expiry: 0\n",
+			wantError: "auth.token_expiry",
+		},
+		{
+			name:   "expiry as duration string",
+			config: "auth:\n  port: 8082\n  jwt_secret: \"0123456789abcdef0123456789abcdef\"\n  token_expiry: \"1d\"\n",
+		},
+		{
+			name:      "unparsable expiry",
+			config:    "auth:\n  port: 8082\n  jwt_secret: \"0123456789abcdef0123456789abcdef\"\n  token_expiry: \"soon\"\n",
+			wantError: "auth.token_expiry: invalid duration",
+		},
+		{
+			name:      "invalid port",
+			config:    "auth:\n  port: -1\n  jwt_secret: \"0123456789abcdef0123456789abcdef\"\n  token_expiry: 3600\n",
+			wantError: "auth.port -1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useConfig(t, tt.config)
+			report := checkConfig()
+
+			errs := strings.Join(report.Errors, "\n")
+			if tt.wantError == "" && !report.OK() {
+				t.Fatalf("unexpected errors: %v", report.Errors)
+			}
+			if tt.wantError != "" && !strings.Contains(errs, tt.wantError) {
+				t.Errorf("errors %v do not mention %q", report.Errors, tt.wantError)
+			}
+			if tt.wantWarning != "" && !strings.Contains(strings.Join(report.Warnings, "\n"), tt.wantWarning) {
+				t.Errorf("warnings %v do not mention %q", report.Warnings, tt.wantWarning)
+			}
+		})
+	}
+}
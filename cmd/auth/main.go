@@ -2,14 +2,19 @@ package main
 
 import (
 	"encoding/json"
+	"flag"
 	"fmt"
 	"log"
 	"net/http"
+	"os"
 	"time"
 
+	"ai-gatway/pkg/apierror"
+	"ai-gatway/pkg/logger"
 	"ai-gatway/pkg/utils"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/sirupsen/logrus"
 )
 
 // TokenRequest 表示一个令牌请求
@@ -30,32 +35,116 @@ var users = map[string]string{
 	"user1": "password1",
 }
 
+// withRequestLogging 记录请求日志，沿用网关传入的请求ID
+func withRequestLogging(appLog logrus.FieldLogger, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get(apierror.RequestIDHeader)
+		if requestID == "" {
+			requestID = utils.NewRequestID()
+			r.Header.Set(apierror.RequestIDHeader, requestID)
+		}
+		w.Header().Set(apierror.RequestIDHeader, requestID)
+
+		start := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		handler(recorder, r)
+
+		appLog.WithFields(logrus.Fields{
+			"route":      r.URL.Path,
+			"method":     r.Method,
+			"request_id": requestID,
+			"status":     recorder.status,
+			"duration":   time.Since(start).String(),
+		}).Info("Auth request")
+	}
+}
+
+// statusRecorder 记录响应状态码
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
 func main() {
+	configFile := flag.String("config", "", "path to the config file (default: configs/config.yaml)")
+	validateConfig := flag.Bool("validate-config", false, "validate the configuration and exit")
+	flag.Parse()
+
+	// 使用指定的配置文件
+	if *configFile != "" {
+		if err := utils.SetConfigFile(*configFile); err != nil {
+			log.Fatalf("Failed to load config: %v", err)
+		}
+	}
+
+	// 仅校验配置，不启动服务
+	if *validateConfig {
+		report := checkConfig()
+		report.Print(os.Stdout, "auth")
+		if !report.OK() {
+			os.Exit(1)
+		}
+		return
+	}
+
 	// 加载配置
 	port, logLevel, jwtSecret, tokenExpiry := utils.GetAuthConfig()
 
+	// 初始化日志
+	_, logFormat, logFile := utils.GetLogConfig("auth")
+	appLog, err := logger.New(logger.Config{
+		Service: "auth",
+		Level:   logLevel,
+		Format:  logFormat,
+		File:    logFile,
+	})
+	if err != nil {
+		log.Fatalf("Failed to initialize logger: %v", err)
+	}
+
 	// 设置路由
-	http.HandleFunc("/auth/token", func(w http.ResponseWriter, r *http.Request) {
+	http.HandleFunc("/auth/token", withRequestLogging(appLog, newTokenHandler(jwtSecret, tokenExpiry)))
+	http.HandleFunc("/auth/validate", withRequestLogging(appLog, newValidateHandler(jwtSecret)))
+
+	http.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+	})
+
+	// 启动服务
+	addr := fmt.Sprintf(":%d", port)
+	appLog.Infof("Auth Service starting on %s with log level %s...", addr, logLevel)
+	appLog.Fatal(http.ListenAndServe(addr, nil))
+}
+
+// newTokenHandler 创建签发令牌的处理函数
+func newTokenHandler(jwtSecret string, tokenExpiry time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			apierror.WriteError(w, r, http.StatusMethodNotAllowed, apierror.CodeMethodNotAllowed, "Method not allowed")
 			return
 		}
 
 		var req TokenRequest
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			apierror.WriteError(w, r, http.StatusBadRequest, apierror.CodeBadRequest, "Invalid request body")
 			return
 		}
 
 		// 验证用户名和密码
 		password, ok := users[req.Username]
 		if !ok || password != req.Password {
-			http.Error(w, "Invalid credentials", http.StatusUnauthorized)
+			apierror.WriteError(w, r, http.StatusUnauthorized, apierror.CodeUnauthorized, "Invalid credentials")
 			return
 		}
 
 		// 创建JWT令牌
-		expiresAt := time.Now().Add(time.Duration(tokenExpiry) * time.Second)
+		expiresAt := time.Now().Add(tokenExpiry)
 		claims := jwt.MapClaims{
 			"sub": req.Username,
 			"exp": expiresAt.Unix(),
@@ -65,7 +154,7 @@ func main() {
 		token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 		tokenString, err := token.SignedString([]byte(jwtSecret))
 		if err != nil {
-			http.Error(w, "Failed to generate token", http.StatusInternalServerError)
+			apierror.WriteError(w, r, http.StatusInternalServerError, apierror.CodeInternal, "Failed to generate token")
 			return
 		}
 
@@ -75,13 +164,16 @@ func main() {
 			Token:     tokenString,
 			ExpiresAt: expiresAt.Unix(),
 		})
-	})
+	}
+}
 
-	http.HandleFunc("/auth/validate", func(w http.ResponseWriter, r *http.Request) {
+// newValidateHandler 创建校验令牌的处理函数
+func newValidateHandler(jwtSecret string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
 		// 从请求头获取令牌
 		authHeader := r.Header.Get("Authorization")
 		if authHeader == "" || len(authHeader) < 7 || authHeader[:7] != "Bearer " {
-			http.Error(w, "Missing or invalid token", http.StatusUnauthorized)
+			apierror.WriteError(w, r, http.StatusUnauthorized, apierror.CodeUnauthorized, "Missing or invalid token")
 			return
 		}
 
@@ -96,22 +188,12 @@ func main() {
 		})
 
 		if err != nil || !token.Valid {
-			http.Error(w, "Invalid token", http.StatusUnauthorized)
+			apierror.WriteError(w, r, http.StatusUnauthorized, apierror.CodeUnauthorized, "Invalid token")
 			return
 		}
 
 		// 令牌有效
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]bool{"valid": true})
-	})
-
-	http.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
-	})
-
-	// 启动服务
-	addr := fmt.Sprintf(":%d", port)
-	log.Printf("Auth Service starting on %s with log level %s...\n", addr, logLevel)
-	log.Fatal(http.ListenAndServe(addr, nil))
+	}
 }
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"ai-gatway/pkg/apierror"
+
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+func TestTokenErrorEnvelope(t *testing.T) {
+	logger, _ := test.NewNullLogger()
+	handler := withRequestLogging(logger, newTokenHandler("secret", time.Minute))
+
+	tests := []struct {
+		name     string
+		method   string
+		body     string
+		wantCode int
+		wantErr  apierror.Code
+	}{
+		{"wrong method", http.MethodGet, "", http.StatusMethodNotAllowed, apierror.CodeMethodNotAllowed},
+		{"malformed body", http.MethodPost, "{", http.StatusBadRequest, apierror.CodeBadRequest},
+		{"bad credentials", http.MethodPost, `{"username":"admin","password":"nope"}`, http.StatusUnauthorized, apierror.CodeUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/auth/token", strings.NewReader(tt.body))
+			req.Header.Set(apierror.RequestIDHeader, "req-1")
+			rec := httptest.NewRecorder()
+			handler(rec, req)
+
+			var envelope apierror.Envelope
+			if err := json.Unmarshal(rec.Body.Bytes(), &envelope); err != nil {
+				t.Fatalf("body is not an error envelope: %s", rec.Body.String())
+			}
+			if rec.Code != tt.wantCode || envelope.Error.Code != tt.wantErr {
+				t.Errorf("got %d %s, want %d %s", rec.Code, envelope.Error.Code, tt.wantCode, tt.wantErr)
+			}
+			if envelope.Error.RequestID != "req-1" || envelope.Error.Message == "" {
+				t.Errorf("envelope missing request_id or message: %+v", envelope.Error)
+			}
+			if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+				t.Errorf("Content-Type = %q", ct)
+			}
+		})
+	}
+}
+
+func TestTokenRoundTrip(t *testing.T) {
+	rec := httptest.NewRecorder()
+	newTokenHandler("secret", time.Minute)(rec, httptest.NewRequest(http.MethodPost, "/auth/token",
+		strings.NewReader(`{"username":"admin","password":"admin123"}`)))
+	var token TokenResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &token); err != nil || token.Token == "" {
+		t.Fatalf("no token issued: %d %s", rec.Code, rec.Body.String())
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/auth/validate", nil)
+	req.Header.Set("Authorization", "Bearer "+token.Token)
+	rec = httptest.NewRecorder()
+	newValidateHandler("secret")(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("valid token rejected: %d %s", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	newValidateHandler("other-secret")(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("token signed with another secret accepted: %d", rec.Code)
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"net/url"
+	"time"
+
+	"ai-gatway/internal/gateway"
+	"ai-gatway/pkg/logger"
+	"ai-gatway/pkg/utils"
+)
+
+// builtinPaths 网关自身注册的路径，路由不能与之重复(否则注册时panic)
+var builtinPaths = map[string]bool{
+	"/health":  true,
+	"/metrics": true,
+}
+
+// checkConfig 校验网关配置，不绑定端口也不注册服务
+func checkConfig() (report *utils.ConfigReport) {
+	report = &utils.ConfigReport{}
+	if !utils.CheckConfigFile(report) {
+		return report
+	}
+
+	// 配置项类型错误时读取函数会panic，转换为报告中的错误
+	defer func() {
+		if rec := recover(); rec != nil {
+			report.Errorf("malformed gateway configuration: %v", rec)
+		}
+	}()
+
+	port, _, targetURL, routes := utils.GetGatewayConfig()
+	if port <= 0 || port > 65535 {
+		report.Errorf("gateway.port %d is not a valid port", port)
+	} else {
+		report.Passf("gateway.port %d", port)
+	}
+
+	if target, err := url.Parse(targetURL); err != nil || target.Scheme == "" || target.Host == "" {
+		report.Errorf("gateway.target_url %q is not a valid absolute URL", targetURL)
+	} else {
+		report.Passf("gateway.target_url %s", targetURL)
+	}
+
+	if utils.CheckMappingList(report, "gateway.routes") {
+		checkRoutes(report, routes)
+	}
+	report.Passf("%d routes", len(routes))
+
+	for _, key := range []string{
+		"gateway.server.read_timeout",
+		"gateway.server.write_timeout",
+		"gateway.proxy.dial_timeout",
+		"gateway.proxy.response_header_timeout",
+	} {
+		utils.CheckDuration(report, key, time.Second)
+	}
+	readTimeout, writeTimeout, maxBodyBytes := utils.GetGatewayServerConfig()
+	if readTimeout < 0 || writeTimeout < 0 || maxBodyBytes < 0 {
+		report.Errorf("gateway.server timeouts and max_body_bytes must not be negative")
+	}
+	dialTimeout, responseHeaderTimeout := utils.GetGatewayProxyConfig()
+	if dialTimeout < 0 || responseHeaderTimeout < 0 {
+		report.Errorf("gateway.proxy timeouts must not be negative")
+	}
+
+	// 启用mTLS时检查证书能否加载
+	tlsEnabled, tlsDefaults, tlsTargets := utils.GetGatewayTLSConfig()
+	if tlsEnabled {
+		configs := map[string]utils.TLSClientConfig{"gateway.tls": tlsDefaults}
+		for host, tc := range tlsTargets {
+			if host == "" {
+				report.Errorf("gateway.tls.targets: every entry needs a host (host:port)")
+				continue
+			}
+			configs["gateway.tls.targets["+host+"]"] = tc
+		}
+		for name, tc := range configs {
+			_, err := gateway.LoadClientTLSConfig(gateway.TLSOptions{
+				CertFile:   tc.CertFile,
+				KeyFile:    tc.KeyFile,
+				CAFile:     tc.CAFile,
+				ServerName: tc.ServerName,
+			})
+			if err != nil {
+				report.Errorf("%s: %v", name, err)
+			} else {
+				report.Passf("%s certificates loaded", name)
+			}
+		}
+	}
+
+	logLevel, logFormat, logFile := utils.GetLogConfig("gateway")
+	if err := (logger.Config{Level: logLevel, Format: logFormat, File: logFile}).Validate(); err != nil {
+		report.Errorf("gateway logging: %v", err)
+	}
+
+	return report
+}
+
+// checkRoutes 校验路由配置，启动时和--validate-config共用
+func checkRoutes(report *utils.ConfigReport, routes []utils.Route) {
+	if len(routes) == 0 {
+		report.Warnf("gateway.routes is empty; only /health and /metrics will be served")
+	}
+	seen := make(map[string]bool)
+	for i, route := range routes {
+		if route.Path == "" || route.Path[0] != '/' {
+			report.Errorf("gateway.routes[%d].path %q must start with /", i, route.Path)
+		}
+		if builtinPaths[route.Path] {
+			report.Errorf("gateway.routes[%d].path %q conflicts with the built-in %s endpoint", i, route.Path, route.Path)
+		} else if seen[route.Path] {
+			report.Errorf("gateway.routes[%d].path %q is duplicated", i, route.Path)
+		}
+		seen[route.Path] = true
+	}
+}
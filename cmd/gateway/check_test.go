@@ -0,0 +1,88 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"ai-gatway/pkg/utils"
+)
+
+// validGatewayConfig 能通过校验的最小网关配置
+const validGatewayConfig = `
+gateway:
+  port: 8081
+  log_level: info
+  log_format: text
+  target_url: "http://localhost:8080"
+  routes:
+    - path: "/v1/chat"
+      target: "http://localhost:8080/mcp/v1/chat"
+      auth_required: true
+`
+
+// useConfig 将配置写入临时文件并加载
+func useConfig(t *testing.T, content string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := utils.SetConfigFile(path); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCheckConfig(t *testing.T) {
+	tests := []struct {
+		name      string
+		replace   [2]string
+		append    string
+		wantError string
+	}{
+		{name: "valid"},
+		{name: "invalid port", replace: [2]string{"port: 8081", "port: 70000"}, wantError: "gateway.port 70000"},
+		{name: "relative target url", replace: [2]string{`"http://localhost:8080"`, `"localhost:8080"`}, wantError: "gateway.target_url"},
+		{name: "bad log format", replace: [2]string{"log_format: text", "log_format: xml"}, wantError: "invalid log format"},
+		{name: "path without slash", append: "    - path: \"v1/models\"\n", wantError: "must start with /"},
+		{name: "duplicate route", append: "    - path: \"/v1/chat\"\n", wantError: "is duplicated"},
+		{name: "builtin health route", append: "    - path: \"/health\"\n", wantError: "conflicts with the built-in /health"},
+		{name: "routes not a list", replace: [2]string{"  routes:\n", "  routes: \"/v1/chat\"\n  unused:\n"}, wantError: "gateway.routes must be a list"},
+		{
+			name:      "tls target without host",
+			replace:   [2]string{"  routes:\n", "  tls:\n    enabled: true\n    targets:\n      - server_name: worker\n  routes:\n"},
+			wantError: "every entry needs a host",
+		},
+		{name: "duration timeouts", replace: [2]string{"  routes:\n", "  proxy:\n    dial_timeout: \"500ms\"\n    response_header_timeout: \"2m\"\n  routes:\n"}},
+		{
+			name:      "unparsable timeout",
+			replace:   [2]string{"  routes:\n", "  server:\n    read_timeout: \"1 minute\"\n  routes:\n"},
+			wantError: "gateway.server.read_timeout: invalid duration",
+		},
+		{name: "builtin metrics route", append: "    - path: \"/metrics\"\n", wantError: "conflicts with the built-in /metrics"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content := validGatewayConfig + tt.append
+			if tt.replace[0] != "" {
+				content = strings.Replace(content, tt.replace[0], tt.replace[1], 1)
+			}
+			useConfig(t, content)
+
+			report := checkConfig()
+			if tt.wantError == "" {
+				if !report.OK() {
+					t.Fatalf("unexpected errors: %v", report.Errors)
+				}
+				return
+			}
+			if report.OK() {
+				t.Fatalf("expected error containing %q, report passed", tt.wantError)
+			}
+			if !strings.Contains(strings.Join(report.Errors, "\n"), tt.wantError) {
+				t.Errorf("errors %v do not mention %q", report.Errors, tt.wantError)
+			}
+		})
+	}
+}
@@ -2,6 +2,8 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
+	"flag"
 	"fmt"
 	"log"
 	"net/http"
@@ -12,6 +14,9 @@ import (
 	"time"
 
 	"ai-gatway/internal/gateway"
+	"ai-gatway/pkg/debug"
+	"ai-gatway/pkg/logger"
+	"ai-gatway/pkg/tracing"
 	"ai-gatway/pkg/utils"
 
 	"github.com/hashicorp/consul/api"
@@ -38,17 +43,85 @@ func registerService(consul *api.Client, serviceID string, port int) error {
 }
 
 func main() {
+	configFile := flag.String("config", "", "path to the config file (default: configs/config.yaml)")
+	validateConfig := flag.Bool("validate-config", false, "validate the configuration and exit")
+	flag.Parse()
+
+	// 使用指定的配置文件
+	if *configFile != "" {
+		if err := utils.SetConfigFile(*configFile); err != nil {
+			log.Fatalf("Failed to load config: %v", err)
+		}
+	}
+
+	// 仅校验配置，不启动服务
+	if *validateConfig {
+		report := checkConfig()
+		report.Print(os.Stdout, "gateway")
+		if !report.OK() {
+			os.Exit(1)
+		}
+		return
+	}
+
+	// 初始化日志
+	logLevel, logFormat, logFile := utils.GetLogConfig("gateway")
+	appLog, err := logger.New(logger.Config{
+		Service: "gateway",
+		Level:   logLevel,
+		Format:  logFormat,
+		File:    logFile,
+	})
+	if err != nil {
+		log.Fatalf("Failed to initialize logger: %v", err)
+	}
+
+	// 初始化链路追踪
+	var shutdownTracing func(context.Context) error
+	tracingEnabled, tracingEndpoint, tracingInsecure, sampleRatio := utils.GetTracingConfig()
+	shutdownTracing, err = tracing.Init(context.Background(), "gateway", tracing.Config{
+		Enabled:     tracingEnabled,
+		Endpoint:    tracingEndpoint,
+		Insecure:    tracingInsecure,
+		SampleRatio: sampleRatio,
+	})
+	if err != nil {
+		appLog.Fatalf("Failed to initialize tracing: %v", err)
+	}
+
+	// 启动性能分析调试端口
+	pprofEnabled, pprofAddr := utils.GetPprofConfig("gateway")
+	debugServer, err := debug.Start(debug.Config{Enabled: pprofEnabled, Addr: pprofAddr}, appLog)
+	if err != nil {
+		appLog.Fatalf("Failed to start debug server: %v", err)
+	}
+
 	// 初始化Consul客户端
 	consulHost, consulPortVal, _, _, _ := utils.GetConsulConfig()
 	consulConfig := api.DefaultConfig()
 	consulConfig.Address = fmt.Sprintf("%s:%d", consulHost, consulPortVal)
 	consulClient, err := api.NewClient(consulConfig)
 	if err != nil {
-		log.Printf("Failed to initialize Consul client: %v", err)
+		appLog.Warnf("Failed to initialize Consul client: %v", err)
 	}
 
 	// 获取网关配置
-	port, _, targetURL, routes := utils.GetGatewayConfig() // Removed unused logLevel
+	port, _, targetURL, routes := utils.GetGatewayConfig()
+
+	// 校验路由配置，存在错误时拒绝启动(与内置端点冲突的路由会在注册时panic)
+	routeReport := &utils.ConfigReport{}
+	if utils.CheckMappingList(routeReport, "gateway.routes") {
+		checkRoutes(routeReport, routes)
+	}
+	for _, warning := range routeReport.Warnings {
+		appLog.Warn(warning)
+	}
+	if !routeReport.OK() {
+		for _, problem := range routeReport.Errors {
+			appLog.Error(problem)
+		}
+		appLog.Fatalf("Invalid gateway route configuration (%d errors), see --validate-config", len(routeReport.Errors))
+	}
 	// Get Auth service configuration for the auth decorator
 	authServicePort, _, _, _ := utils.GetAuthConfig()
 	authServiceURL := fmt.Sprintf("http://localhost:%d", authServicePort) // Assuming auth service is on localhost
@@ -57,26 +130,63 @@ func main() {
 	serviceID := fmt.Sprintf("gateway-%d", port)
 	if consulClient != nil {
 		if err := registerService(consulClient, serviceID, port); err != nil {
-			log.Printf("Failed to register service with Consul: %v", err)
+			appLog.Warnf("Failed to register service with Consul: %v", err)
 		} else {
-			log.Printf("Successfully registered service %s with Consul", serviceID)
+			appLog.Infof("Successfully registered service %s with Consul", serviceID)
 		}
 	} else {
-		log.Printf("Skipping Consul registration as client failed to initialize.")
+		appLog.Infof("Skipping Consul registration as client failed to initialize.")
 	}
 
 	// 创建目标URL
 	target, err := url.Parse(targetURL)
 	if err != nil {
-		log.Fatalf("Invalid target URL: %v", err)
+		appLog.Fatalf("Invalid target URL: %v", err)
+	}
+
+	// 加载后端mTLS配置(默认配置和按host:port覆盖的配置)，证书错误时启动失败
+	var backendTLS *tls.Config
+	targetTLS := make(map[string]*tls.Config)
+	tlsEnabled, tlsDefaults, tlsTargets := utils.GetGatewayTLSConfig()
+	if tlsEnabled {
+		backendTLS, err = loadBackendTLS(tlsDefaults)
+		if err != nil {
+			appLog.Fatalf("Invalid backend TLS configuration: %v", err)
+		}
+		appLog.Info("Using mTLS for backend connections")
+		for host, tlsOptions := range tlsTargets {
+			if host == "" {
+				appLog.Fatalf("Invalid backend TLS configuration: every gateway.tls.targets entry needs a host (host:port)")
+			}
+			targetTLS[host], err = loadBackendTLS(tlsOptions)
+			if err != nil {
+				appLog.Fatalf("Invalid backend TLS configuration for %s: %v", host, err)
+			}
+			appLog.Infof("Using mTLS for backend %s", host)
+		}
+		if target.Scheme != "https" {
+			appLog.Warnf("Backend TLS is enabled but target %s does not use https; client certificates will not be sent", targetURL)
+		}
 	}
 
-	// 创建反向代理 (Base Gateway)
-	baseProxy := gateway.NewBaseGatewayWithTarget(target)
+	// 创建反向代理 (Base Gateway)，同一后端共享连接池，流式路由使用立即刷新的代理
+	dialTimeout, responseHeaderTimeout := utils.GetGatewayProxyConfig()
+	proxies := gateway.NewProxyPool(gateway.ProxyOptions{
+		Logger:                appLog,
+		TLSConfig:             backendTLS,
+		DialTimeout:           dialTimeout,
+		ResponseHeaderTimeout: responseHeaderTimeout,
+	}, targetTLS)
+	baseProxy := proxies.Get(target, 0)
+	streamingProxy := proxies.Get(target, -1)
 
 	// 设置路由
+	readTimeout, writeTimeout, maxBodyBytes := utils.GetGatewayServerConfig()
 	for _, route := range routes {
 		var currentGateway gateway.Gateway = baseProxy
+		if route.Streaming {
+			currentGateway = streamingProxy
+		}
 
 		// Wrap with Auth decorator if required
 		if route.AuthRequired {
@@ -86,31 +196,43 @@ func main() {
 			currentGateway = gateway.WithAuth(currentGateway, authMap, authServiceURL)
 		}
 
+		// 限制请求体大小
+		currentGateway = gateway.WithBodyLimit(currentGateway, maxBodyBytes)
+
 		// Wrap with Logging decorator
-		loggedGateway := gateway.WithLogging(currentGateway)
+		loggedGateway := gateway.WithLogging(currentGateway, appLog.WithField("route", route.Path))
 
 		// http.Handle expects an http.Handler. We adapt our gateway.Gateway.
-		http.Handle(route.Path, http.HandlerFunc(loggedGateway.HandleRequest))
+		http.Handle(route.Path, tracing.Handler(http.HandlerFunc(loggedGateway.HandleRequest), route.Path))
 	}
 
+	// 暴露网关指标
+	http.Handle("/metrics", gateway.MetricsHandler())
+
 	// 添加健康检查端点
 	http.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 	})
 
-	// 启动服务器
+	// 启动服务器(大文件上传和流式响应需要较长的读写超时)
 	server := &http.Server{
 		Addr:         fmt.Sprintf(":%d", port),
 		ReadTimeout:  10 * time.Second,
 		WriteTimeout: 10 * time.Second,
 		IdleTimeout:  60 * time.Second,
 	}
+	if readTimeout > 0 {
+		server.ReadTimeout = readTimeout
+	}
+	if writeTimeout > 0 {
+		server.WriteTimeout = writeTimeout
+	}
 
 	// Start server in a goroutine
 	go func() {
-		log.Printf("Starting gateway server on port %d", port)
+		appLog.Infof("Starting gateway server on port %d", port)
 		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("Could not start gateway server: %v", err)
+			appLog.Fatalf("Could not start gateway server: %v", err)
 		}
 	}()
 
@@ -118,7 +240,7 @@ func main() {
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
-	log.Println("Shutting down gateway server...")
+	appLog.Info("Shutting down gateway server...")
 
 	// Create a deadline to wait for.
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
@@ -126,18 +248,36 @@ func main() {
 
 	// Attempt to gracefully shut down the server
 	if err := server.Shutdown(ctx); err != nil {
-		log.Printf("Gateway server forced to shutdown: %v", err)
+		appLog.Warnf("Gateway server forced to shutdown: %v", err)
+	}
+	if debugServer != nil {
+		debugServer.Close()
 	}
 
 	// Deregister from Consul
 	if consulClient != nil {
-		log.Printf("Deregistering service %s from Consul", serviceID)
+		appLog.Infof("Deregistering service %s from Consul", serviceID)
 		if err := consulClient.Agent().ServiceDeregister(serviceID); err != nil {
-			log.Printf("Failed to deregister service %s from Consul: %v", serviceID, err)
+			appLog.Warnf("Failed to deregister service %s from Consul: %v", serviceID, err)
 		} else {
-			log.Printf("Successfully deregistered service %s from Consul", serviceID)
+			appLog.Infof("Successfully deregistered service %s from Consul", serviceID)
 		}
 	}
 
-	log.Println("Gateway server exiting.")
+	// 刷新未导出的追踪数据
+	if err := shutdownTracing(ctx); err != nil {
+		appLog.Warnf("Failed to flush traces: %v", err)
+	}
+
+	appLog.Info("Gateway server exiting.")
+}
+
+// loadBackendTLS 加载访问后端的mTLS客户端配置
+func loadBackendTLS(cfg utils.TLSClientConfig) (*tls.Config, error) {
+	return gateway.LoadClientTLSConfig(gateway.TLSOptions{
+		CertFile:   cfg.CertFile,
+		KeyFile:    cfg.KeyFile,
+		CAFile:     cfg.CAFile,
+		ServerName: cfg.ServerName,
+	})
 }
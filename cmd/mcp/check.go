@@ -0,0 +1,101 @@
+package main
+
+import (
+	"net/url"
+	"sort"
+	"time"
+
+	"ai-gatway/pkg/logger"
+	"ai-gatway/pkg/utils"
+)
+
+// checkConfig 校验MCP服务配置，不绑定端口也不探测工作节点
+func checkConfig() (report *utils.ConfigReport) {
+	report = &utils.ConfigReport{}
+	if !utils.CheckConfigFile(report) {
+		return report
+	}
+
+	// 配置项类型错误时读取函数会panic，转换为报告中的错误
+	defer func() {
+		if rec := recover(); rec != nil {
+			report.Errorf("malformed mcp or models configuration: %v", rec)
+		}
+	}()
+
+	port, logLevel, workers := utils.GetMCPConfig()
+	if port <= 0 || port > 65535 {
+		report.Errorf("mcp.port %d is not a valid port", port)
+	} else {
+		report.Passf("mcp.port %d", port)
+	}
+
+	models := utils.GetModelsConfig()
+	if utils.CheckMappingList(report, "mcp.workers") {
+		checkWorkers(report, workers, models)
+	}
+	report.Passf("%d workers, %d models", len(workers), len(models))
+
+	for _, key := range []string{"mcp.health_check.timeout", "mcp.health_check.cache_ttl", "mcp.health_check.interval"} {
+		utils.CheckDuration(report, key, time.Second)
+	}
+
+	_, logFormat, logFile := utils.GetLogConfig("mcp")
+	if err := (logger.Config{Level: logLevel, Format: logFormat, File: logFile}).Validate(); err != nil {
+		report.Errorf("mcp logging: %v", err)
+	}
+
+	return report
+}
+
+// checkWorkers 校验工作节点配置，启动时和--validate-config共用
+func checkWorkers(report *utils.ConfigReport, workers []utils.Worker, models map[string]utils.ModelInfo) {
+	if len(workers) == 0 {
+		report.Errorf("mcp.workers is empty")
+		return
+	}
+
+	names := make(map[string]bool)
+	served := make(map[string]bool)
+	for i, worker := range workers {
+		if worker.Name == "" {
+			report.Errorf("mcp.workers[%d]: name is required", i)
+		} else if names[worker.Name] {
+			report.Errorf("mcp.workers[%d]: duplicate worker name %q", i, worker.Name)
+		}
+		names[worker.Name] = true
+
+		if worker.URL == "" {
+			report.Errorf("mcp.workers[%d] (%s): url is required", i, worker.Name)
+		} else if u, err := url.Parse(worker.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			report.Errorf("mcp.workers[%d] (%s): url %q is not a valid http(s) URL", i, worker.Name, worker.URL)
+		}
+
+		if worker.Model == "" {
+			report.Errorf("mcp.workers[%d] (%s): model is required", i, worker.Name)
+		} else if _, ok := models[worker.Model]; !ok {
+			report.Warnf("mcp.workers[%d] (%s): model %q is not defined under models, requests for it will be rejected", i, worker.Name, worker.Model)
+		}
+		served[worker.Model] = true
+
+		// 负值会被当作未设置而静默使用默认值，视为配置错误
+		if worker.MaxTokens < 0 {
+			report.Errorf("mcp.workers[%d] (%s): max_tokens %d must not be negative", i, worker.Name, worker.MaxTokens)
+		}
+		if worker.Timeout < 0 {
+			report.Errorf("mcp.workers[%d] (%s): timeout %d must not be negative", i, worker.Name, worker.Timeout)
+		}
+	}
+
+	// 按模型ID排序，启动日志和校验报告的顺序保持稳定
+	ids := make([]string, 0, len(models))
+	for id := range models {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		if !served[id] {
+			report.Warnf("models.%s has no workers configured", id)
+		}
+	}
+}
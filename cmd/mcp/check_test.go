@@ -0,0 +1,205 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"ai-gatway/pkg/utils"
+)
+
+// useConfig 将配置写入临时文件并加载
+func useConfig(t *testing.T, content string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := utils.SetConfigFile(path); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCheckConfig(t *testing.T) {
+	tests := []struct {
+		name      string
+		config    string
+		wantError string
+	}{
+		{
+			name: "valid",
+			config: `
+mcp:
+  port: 8080
+  workers:
+    - name: "w1"
+      url: "http://localhost:5000"
+      model: "m"
+models:
+  m:
+    name: "M"
+`,
+		},
+		{
+			name:      "no workers",
+			config:    "mcp:\n  port: 8080\n",
+			wantError: "mcp.workers is empty",
+		},
+		{
+			name: "invalid port",
+			config: `
+mcp:
+  port: 0
+  workers:
+    - name: "w1"
+      url: "http://localhost:5000"
+      model: "m"
+`,
+			wantError: "mcp.port 0",
+		},
+		{
+			name: "malformed workers",
+			config: `
+mcp:
+  port: 8080
+  workers: "not a list"
+`,
+			wantError: "mcp.workers must be a list, got string",
+		},
+		{
+			name: "worker is not a mapping",
+			config: `
+mcp:
+  port: 8080
+  workers:
+    - "http://worker:5000"
+`,
+			wantError: "mcp.workers[0] must be a mapping, got string",
+		},
+		{
+			name: "no workers",
+			config: `
+mcp:
+  port: 8080
+`,
+			wantError: "mcp.workers is empty",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useConfig(t, tt.config)
+			report := checkConfig()
+
+			if tt.wantError == "" {
+				if !report.OK() {
+					t.Fatalf("unexpected errors: %v", report.Errors)
+				}
+				return
+			}
+			if !strings.Contains(strings.Join(report.Errors, "\n"), tt.wantError) {
+				t.Errorf("errors %v do not mention %q", report.Errors, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestCheckWorkers(t *testing.T) {
+	models := map[string]utils.ModelInfo{"m": {Name: "M"}, "idle": {Name: "Idle"}}
+	tests := []struct {
+		name        string
+		workers     []utils.Worker
+		wantError   string
+		wantWarning string
+	}{
+		{
+			name:        "valid with idle model",
+			workers:     []utils.Worker{{Name: "w1", URL: "https://worker:5000", Model: "m"}},
+			wantWarning: "models.idle has no workers configured",
+		},
+		{name: "empty", wantError: "mcp.workers is empty"},
+		{
+			name:      "missing name",
+			workers:   []utils.Worker{{URL: "http://worker:5000", Model: "m"}},
+			wantError: "name is required",
+		},
+		{
+			name: "duplicate name",
+			workers: []utils.Worker{
+				{Name: "w1", URL: "http://a:5000", Model: "m"},
+				{Name: "w1", URL: "http://b:5000", Model: "m"},
+			},
+			wantError: `duplicate worker name "w1"`,
+		},
+		{
+			name:      "empty url",
+			workers:   []utils.Worker{{Name: "w1", Model: "m"}},
+			wantError: "url is required",
+		},
+		{
+			name:      "url without scheme",
+			workers:   []utils.Worker{{Name: "w1", URL: "worker:5000", Model: "m"}},
+			wantError: "is not a valid http(s) URL",
+		},
+		{
+			name:      "unparseable url",
+			workers:   []utils.Worker{{Name: "w1", URL: "http://[::1", Model: "m"}},
+			wantError: "is not a valid http(s) URL",
+		},
+		{
+			name:      "missing model",
+			workers:   []utils.Worker{{Name: "w1", URL: "http://worker:5000"}},
+			wantError: "model is required",
+		},
+		{
+			name:        "model not in catalog",
+			workers:     []utils.Worker{{Name: "w1", URL: "http://worker:5000", Model: "other"}},
+			wantWarning: `model "other" is not defined under models`,
+		},
+		{
+			name:      "negative max_tokens",
+			workers:   []utils.Worker{{Name: "w1", URL: "http://worker:5000", Model: "m", MaxTokens: -1}},
+			wantError: "max_tokens -1 must not be negative",
+		},
+		{
+			name:      "negative timeout",
+			workers:   []utils.Worker{{Name: "w1", URL: "http://worker:5000", Model: "m", Timeout: -30}},
+			wantError: "timeout -30 must not be negative",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := &utils.ConfigReport{}
+			checkWorkers(report, tt.workers, models)
+
+			errs := strings.Join(report.Errors, "\n")
+			if tt.wantError == "" && !report.OK() {
+				t.Fatalf("unexpected errors: %v", report.Errors)
+			}
+			if tt.wantError != "" && !strings.Contains(errs, tt.wantError) {
+				t.Errorf("errors %v do not mention %q", report.Errors, tt.wantError)
+			}
+			if tt.wantWarning != "" && !strings.Contains(strings.Join(report.Warnings, "\n"), tt.wantWarning) {
+				t.Errorf("warnings %v do not mention %q", report.Warnings, tt.wantWarning)
+			}
+		})
+	}
+}
+
+func TestCheckWorkersWarningOrder(t *testing.T) {
+	models := map[string]utils.ModelInfo{"c": {}, "a": {}, "d": {}, "b": {}}
+	workers := []utils.Worker{{Name: "w1", URL: "http://worker:5000", Model: "x"}}
+
+	report := &utils.ConfigReport{}
+	checkWorkers(report, workers, models)
+	want := []string{
+		`mcp.workers[0] (w1): model "x" is not defined under models, requests for it will be rejected`,
+		"models.a has no workers configured",
+		"models.b has no workers configured",
+		"models.c has no workers configured",
+		"models.d has no workers configured",
+	}
+	if strings.Join(report.Warnings, "\n") != strings.Join(want, "\n") {
+		t.Errorf("warnings = %q, want %q", report.Warnings, want)
+	}
+}
@@ -1,19 +1,98 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"ai-gatway/internal/mcp"
+	"ai-gatway/pkg/debug"
+	"ai-gatway/pkg/httpx"
+	"ai-gatway/pkg/logger"
+	"ai-gatway/pkg/tracing"
 	"ai-gatway/pkg/utils"
+
+	"github.com/sirupsen/logrus"
 )
 
 func main() {
+	configFile := flag.String("config", "", "path to the config file (default: configs/config.yaml)")
+	validateConfig := flag.Bool("validate-config", false, "validate the configuration and exit")
+	flag.Parse()
+
+	// 使用指定的配置文件
+	if *configFile != "" {
+		if err := utils.SetConfigFile(*configFile); err != nil {
+			log.Fatalf("Failed to load config: %v", err)
+		}
+	}
+
+	// 仅校验配置，不启动服务
+	if *validateConfig {
+		report := checkConfig()
+		report.Print(os.Stdout, "mcp")
+		if !report.OK() {
+			os.Exit(1)
+		}
+		return
+	}
+
 	// 加载配置
 	port, logLevel, workers := utils.GetMCPConfig()
 	models := utils.GetModelsConfig()
 
+	// 初始化日志
+	_, logFormat, logFile := utils.GetLogConfig("mcp")
+	appLog, err := logger.New(logger.Config{
+		Service: "mcp",
+		Level:   logLevel,
+		Format:  logFormat,
+		File:    logFile,
+	})
+	if err != nil {
+		log.Fatalf("Failed to initialize logger: %v", err)
+	}
+
+	// 初始化链路追踪
+	tracingEnabled, tracingEndpoint, tracingInsecure, sampleRatio := utils.GetTracingConfig()
+	shutdownTracing, err := tracing.Init(context.Background(), "mcp", tracing.Config{
+		Enabled:     tracingEnabled,
+		Endpoint:    tracingEndpoint,
+		Insecure:    tracingInsecure,
+		SampleRatio: sampleRatio,
+	})
+	if err != nil {
+		appLog.Fatalf("Failed to initialize tracing: %v", err)
+	}
+
+	// 启动性能分析调试端口
+	pprofEnabled, pprofAddr := utils.GetPprofConfig("mcp")
+	debugServer, err := debug.Start(debug.Config{Enabled: pprofEnabled, Addr: pprofAddr}, appLog)
+	if err != nil {
+		appLog.Fatalf("Failed to start debug server: %v", err)
+	}
+
+	// 校验工作节点配置，存在错误时拒绝启动
+	workerReport := &utils.ConfigReport{}
+	if utils.CheckMappingList(workerReport, "mcp.workers") {
+		checkWorkers(workerReport, workers, models)
+	}
+	for _, warning := range workerReport.Warnings {
+		appLog.Warn(warning)
+	}
+	if !workerReport.OK() {
+		for _, problem := range workerReport.Errors {
+			appLog.Error(problem)
+		}
+		appLog.Fatalf("Invalid model worker configuration (%d errors), see --validate-config", len(workerReport.Errors))
+	}
+
 	// 转换工作节点格式
 	var modelWorkers []mcp.ModelWorker
 	for _, worker := range workers {
@@ -37,29 +116,104 @@ func main() {
 			Description:   info.Description,
 			ContextLength: info.ContextLength,
 			Capabilities:  info.Capabilities,
+			SystemPrompt:  info.SystemPrompt,
 		}
 	}
 
 	// 创建模型服务
 	modelService := mcp.NewModelService(modelWorkers, modelInfoMap)
+	modelService.Logger = appLog
+
+	// 配置访问工作节点的HTTP客户端(超时由各工作节点配置决定)
+	maxRetries, retryBackoffMs, rateLimit, burst := utils.GetHTTPClientConfig()
+	transport := utils.GetHTTPTransportConfig()
+	clientConfig := httpx.Config{
+		MaxRetries:   maxRetries,
+		RetryBackoff: time.Duration(retryBackoffMs) * time.Millisecond,
+		RateLimit:    rateLimit,
+		Burst:        burst,
+	}
+	if debug, maxBodyBytes := utils.GetHTTPClientDebugConfig(); debug {
+		clientConfig.Hook = &httpx.LogHook{
+			Logger:       appLog.WithField("component", "upstream"),
+			MaxBodyBytes: maxBodyBytes,
+		}
+	}
+	modelService.SetClientConfig(clientConfig, httpx.TransportConfig{
+		MaxIdleConns:        transport.MaxIdleConns,
+		MaxIdleConnsPerHost: transport.MaxIdleConnsPerHost,
+		MaxConnsPerHost:     transport.MaxConnsPerHost,
+		IdleConnTimeout:     time.Duration(transport.IdleConnTimeout) * time.Second,
+		DialTimeout:         time.Duration(transport.DialTimeout) * time.Second,
+		TLSHandshakeTimeout: time.Duration(transport.TLSHandshakeTimeout) * time.Second,
+		DisableHTTP2:        transport.DisableHTTP2,
+	})
+
+	// 限制请求体和上游响应体大小
+	modelService.SetBodyLimits(utils.GetMCPLimitsConfig())
+
+	// 配置工作节点健康检查
+	healthTimeout, healthCacheTTL, healthInterval := utils.GetMCPHealthConfig()
+	modelService.Health = modelService.NewHealthChecker(healthTimeout, healthCacheTTL)
+	healthCtx, stopHealth := context.WithCancel(context.Background())
+	defer stopHealth()
+	modelService.Health.Start(healthCtx, healthInterval)
 
 	// 创建基础MCP服务
 	baseService := mcp.NewBaseService()
 
-	// 使用装饰器模式添加功能
-	service := mcp.WithLogging(baseService)
-	service = mcp.WithModelService(service, modelService)
+	// 使用装饰器模式添加功能(日志装饰器在最外层，所有路由都带请求ID和访问日志)
+	service := mcp.WithModelService(baseService, modelService)
+	service = mcp.WithLogging(service, appLog)
 
 	// 设置HTTP路由
-	http.HandleFunc("/mcp", service.HandleRequest)
-	http.HandleFunc("/mcp/v1/chat/completions", service.HandleRequest)
-	http.HandleFunc("/mcp/v1/chat", service.HandleRequest)
-	http.HandleFunc("/mcp/v1/models", service.HandleRequest)
-	http.HandleFunc("/health", service.HandleRequest)
+	handler := http.HandlerFunc(service.HandleRequest)
+	http.Handle("/mcp", tracing.Handler(handler, "/mcp"))
+	http.Handle("/mcp/v1/chat/completions", tracing.Handler(handler, "/mcp/v1/chat/completions"))
+	http.Handle("/mcp/v1/chat", tracing.Handler(handler, "/mcp/v1/chat"))
+	http.Handle("/mcp/v1/models", tracing.Handler(handler, "/mcp/v1/models"))
+	http.Handle("/mcp/v1/stats", handler)
+	http.Handle("/health", handler)
+	http.Handle("/metrics", mcp.MetricsHandler())
 
 	// 启动服务
 	addr := fmt.Sprintf(":%d", port)
-	log.Printf("MCP Server starting on %s with log level %s...\n", addr, logLevel)
-	log.Printf("Loaded %d model workers and %d model definitions\n", len(modelWorkers), len(modelInfoMap))
-	log.Fatal(http.ListenAndServe(addr, nil))
+	appLog.Infof("MCP Server starting on %s with log level %s...", addr, logLevel)
+	appLog.Infof("Loaded %d model workers and %d model definitions", len(modelWorkers), len(modelInfoMap))
+	for _, worker := range modelWorkers {
+		appLog.WithFields(logrus.Fields{
+			"worker":    worker.Name,
+			"url":       worker.URL,
+			"model":     worker.Model,
+			"priority":  worker.Priority,
+			"streaming": worker.Streaming,
+		}).Info("Model worker configured")
+	}
+	server := &http.Server{Addr: addr}
+	go func() {
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			appLog.Fatalf("Could not start MCP server: %v", err)
+		}
+	}()
+
+	// 收到退出信号后停止接收请求，等待进行中的请求完成
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+	appLog.Info("Shutting down MCP server...")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		appLog.Warnf("MCP server forced to shutdown: %v", err)
+	}
+	stopHealth()
+	if debugServer != nil {
+		debugServer.Close()
+	}
+
+	// 刷新未导出的追踪数据
+	if err := shutdownTracing(ctx); err != nil {
+		appLog.Warnf("Failed to flush traces: %v", err)
+	}
 }
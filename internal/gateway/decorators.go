@@ -1,25 +1,35 @@
 package gateway
 
 import (
+	"bufio"
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"io"
 	"net/http"
-	"net/url"
 	"strings"
+
+	"ai-gatway/pkg/apierror"
 )
 
 // RouteDecorator 路由装饰器
 type RouteDecorator struct {
 	gateway Gateway
 	routes  map[string]string
+	proxies *ProxyPool
 }
 
 // WithRouting 添加路由功能的装饰器
 func WithRouting(gateway Gateway, routes map[string]string) Gateway {
+	return WithRoutingPool(gateway, routes, defaultProxyPool)
+}
+
+// WithRoutingPool 添加路由功能的装饰器，使用指定代理池访问路由目标
+func WithRoutingPool(gateway Gateway, routes map[string]string, proxies *ProxyPool) Gateway {
 	return &RouteDecorator{
 		gateway: gateway,
 		routes:  routes,
+		proxies: proxies,
 	}
 }
 
@@ -31,10 +41,10 @@ func (d *RouteDecorator) HandleRequest(w http.ResponseWriter, r *http.Request) {
 	// 检查是否有匹配的路由规则
 	for pattern, targetURL := range d.routes {
 		if strings.HasPrefix(path, pattern) {
-			// 创建新的请求目标
-			target, err := url.Parse(targetURL)
+			// 获取目标的代理(按目标缓存，复用连接)
+			proxy, err := d.proxies.GetURL(targetURL, 0)
 			if err != nil {
-				http.Error(w, "Internal routing error", http.StatusInternalServerError)
+				apierror.WriteError(w, r, http.StatusInternalServerError, apierror.CodeInternal, "Internal routing error")
 				return
 			}
 
@@ -44,8 +54,6 @@ func (d *RouteDecorator) HandleRequest(w http.ResponseWriter, r *http.Request) {
 				r.URL.Path = "/"
 			}
 
-			// 创建临时的反向代理
-			proxy := NewBaseGatewayWithTarget(target)
 			proxy.HandleRequest(w, r)
 			return
 		}
@@ -89,14 +97,14 @@ func (d *AuthDecorator) HandleRequest(w http.ResponseWriter, r *http.Request) {
 		// 获取认证令牌
 		token := r.Header.Get("Authorization")
 		if token == "" {
-			http.Error(w, "Unauthorized: Missing token", http.StatusUnauthorized)
+			apierror.WriteError(w, r, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized: Missing token")
 			return
 		}
 
 		// 在实际实现中，这里应该调用认证服务验证令牌
 		// 简化起见，这里只检查令牌格式
 		if !strings.HasPrefix(token, "Bearer ") {
-			http.Error(w, "Unauthorized: Invalid token format", http.StatusUnauthorized)
+			apierror.WriteError(w, r, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized: Invalid token format")
 			return
 		}
 
@@ -111,13 +119,20 @@ func (d *AuthDecorator) HandleRequest(w http.ResponseWriter, r *http.Request) {
 type ModelRoutingDecorator struct {
 	gateway      Gateway
 	modelWorkers map[string]string
+	proxies      *ProxyPool
 }
 
 // WithModelRouting 添加模型路由功能的装饰器
 func WithModelRouting(gateway Gateway, modelWorkers map[string]string) Gateway {
+	return WithModelRoutingPool(gateway, modelWorkers, defaultProxyPool)
+}
+
+// WithModelRoutingPool 添加模型路由功能的装饰器，使用指定代理池访问模型worker
+func WithModelRoutingPool(gateway Gateway, modelWorkers map[string]string, proxies *ProxyPool) Gateway {
 	return &ModelRoutingDecorator{
 		gateway:      gateway,
 		modelWorkers: modelWorkers,
+		proxies:      proxies,
 	}
 }
 
@@ -130,42 +145,17 @@ func (d *ModelRoutingDecorator) HandleRequest(w http.ResponseWriter, r *http.Req
 	if modelName == "" && r.Method == "POST" &&
 		(strings.Contains(r.URL.Path, "/chat/completions") ||
 			strings.Contains(r.URL.Path, "/completions")) {
-
-		// 尝试读取请求体以查找模型名称
-		var requestData map[string]interface{}
-
-		// 保存请求体内容，因为读取后正文将被消费
-		bodyBytes, err := io.ReadAll(r.Body)
-		if err == nil {
-			// 重新设置请求体，使其可以被后续处理
-			r.Body = io.NopCloser(bytes.NewBuffer(bodyBytes))
-
-			// 解析JSON请求体
-			err = json.Unmarshal(bodyBytes, &requestData)
-			if err == nil && requestData["model"] != nil {
-				// 从请求体中提取模型名称
-				if modelStr, ok := requestData["model"].(string); ok {
-					modelName = modelStr
-				}
-			}
-
-			// 恢复请求体以供后续处理
-			r.Body = io.NopCloser(bytes.NewBuffer(bodyBytes))
-		}
+		modelName = modelFromBody(r)
 	}
 
 	// 根据模型名称选择对应的worker服务
 	if modelName != "" && d.modelWorkers[modelName] != "" {
 		// 找到对应的模型worker
-		workerURL := d.modelWorkers[modelName]
-		target, err := url.Parse(workerURL)
+		proxy, err := d.proxies.GetURL(d.modelWorkers[modelName], 0)
 		if err != nil {
-			http.Error(w, "Internal routing error", http.StatusInternalServerError)
+			apierror.WriteError(w, r, http.StatusInternalServerError, apierror.CodeInternal, "Internal routing error")
 			return
 		}
-
-		// 创建临时的反向代理
-		proxy := NewBaseGatewayWithTarget(target)
 		proxy.HandleRequest(w, r)
 		return
 	}
@@ -173,3 +163,80 @@ func (d *ModelRoutingDecorator) HandleRequest(w http.ResponseWriter, r *http.Req
 	// 没有找到对应的模型worker，使用默认处理
 	d.gateway.HandleRequest(w, r)
 }
+
+// readCloser 组合读取端和原始请求体的Close
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// modelFromBody 从JSON请求体中读取model字段，无论成功与否请求体都恢复为原始内容。
+// 声明了非JSON类型(如multipart上传)的请求体不做缓冲；未声明类型时根据开头的字节判断。
+func modelFromBody(r *http.Request) string {
+	original := r.Body
+	if original == nil || original == http.NoBody {
+		return ""
+	}
+
+	contentType := r.Header.Get("Content-Type")
+	if contentType != "" && !strings.HasPrefix(contentType, "application/json") {
+		return ""
+	}
+	if contentType == "" {
+		sniffer := bufio.NewReader(original)
+		r.Body = readCloser{sniffer, original}
+		prefix, _ := sniffer.Peek(512)
+		if trimmed := bytes.TrimLeft(prefix, " \t\r\n"); len(trimmed) == 0 || trimmed[0] != '{' {
+			return ""
+		}
+	}
+
+	// 读取失败(如超过大小上限)时，后续处理器读到已读部分后会得到同样的错误
+	bodyBytes, err := io.ReadAll(r.Body)
+	r.Body = readCloser{io.MultiReader(bytes.NewReader(bodyBytes), r.Body), original}
+	if err != nil {
+		return ""
+	}
+
+	// 解析JSON请求体
+	var requestData map[string]interface{}
+	if err := json.Unmarshal(bodyBytes, &requestData); err != nil {
+		return ""
+	}
+	modelStr, _ := requestData["model"].(string)
+	return modelStr
+}
+
+// BodyLimitDecorator 请求体大小限制装饰器
+type BodyLimitDecorator struct {
+	gateway  Gateway
+	maxBytes int64
+}
+
+// WithBodyLimit 添加请求体大小限制的装饰器，maxBytes<=0时不限制
+func WithBodyLimit(gateway Gateway, maxBytes int64) Gateway {
+	if maxBytes <= 0 {
+		return gateway
+	}
+	return &BodyLimitDecorator{
+		gateway:  gateway,
+		maxBytes: maxBytes,
+	}
+}
+
+// HandleRequest 检查请求体大小，超出限制时返回413
+func (d *BodyLimitDecorator) HandleRequest(w http.ResponseWriter, r *http.Request) {
+	// 声明了Content-Length时提前拒绝
+	if r.ContentLength > d.maxBytes {
+		apierror.WriteError(w, r, http.StatusRequestEntityTooLarge, apierror.CodePayloadTooLarge,
+			fmt.Sprintf("Request body exceeds %d bytes", d.maxBytes))
+		return
+	}
+
+	// 分块传输时在读取过程中限制，请求体仍以流的方式转发，不做缓冲
+	if r.Body != nil && r.Body != http.NoBody {
+		r.Body = http.MaxBytesReader(w, r.Body, d.maxBytes)
+	}
+
+	d.gateway.HandleRequest(w, r)
+}
@@ -0,0 +1,247 @@
+package gateway
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"ai-gatway/pkg/apierror"
+
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+// decodeEnvelope 解析错误信封
+func decodeEnvelope(t *testing.T, rec *httptest.ResponseRecorder) apierror.Envelope {
+	t.Helper()
+	var envelope apierror.Envelope
+	if err := json.Unmarshal(rec.Body.Bytes(), &envelope); err != nil {
+		t.Fatalf("body is not an error envelope: %s", rec.Body.String())
+	}
+	return envelope
+}
+
+func TestGatewayErrorEnvelopes(t *testing.T) {
+	logger, _ := test.NewNullLogger()
+	dead := httptest.NewServer(http.NotFoundHandler())
+	dead.Close()
+	deadURL, _ := url.Parse(dead.URL)
+
+	tests := []struct {
+		name     string
+		gateway  Gateway
+		request  func() *http.Request
+		wantCode int
+		wantErr  apierror.Code
+	}{
+		{
+			name:    "missing token",
+			gateway: WithAuth(NewBaseGatewayWithTarget(deadURL), map[string]bool{"/v1": true}, ""),
+			request: func() *http.Request {
+				return httptest.NewRequest(http.MethodGet, "/v1/models", nil)
+			},
+			wantCode: http.StatusUnauthorized,
+			wantErr:  apierror.CodeUnauthorized,
+		},
+		{
+			name:    "backend down",
+			gateway: NewBaseGatewayWithOptions(deadURL, ProxyOptions{Logger: logger}),
+			request: func() *http.Request {
+				return httptest.NewRequest(http.MethodGet, "/v1/models", nil)
+			},
+			wantCode: http.StatusBadGateway,
+			wantErr:  apierror.CodeUpstreamUnavailable,
+		},
+		{
+			name:    "body too large",
+			gateway: WithBodyLimit(NewBaseGatewayWithTarget(deadURL), 4),
+			request: func() *http.Request {
+				return httptest.NewRequest(http.MethodPost, "/v1/chat", strings.NewReader("too large"))
+			},
+			wantCode: http.StatusRequestEntityTooLarge,
+			wantErr:  apierror.CodePayloadTooLarge,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gateway := WithLogging(tt.gateway, logger)
+			rec := httptest.NewRecorder()
+			gateway.HandleRequest(rec, tt.request())
+
+			envelope := decodeEnvelope(t, rec)
+			if rec.Code != tt.wantCode || envelope.Error.Code != tt.wantErr {
+				t.Errorf("got %d %s, want %d %s", rec.Code, envelope.Error.Code, tt.wantCode, tt.wantErr)
+			}
+			requestID := rec.Header().Get(apierror.RequestIDHeader)
+			if requestID == "" || envelope.Error.RequestID != requestID {
+				t.Errorf("envelope request_id = %q, header = %q", envelope.Error.RequestID, requestID)
+			}
+		})
+	}
+}
+
+// recordingBackend 记录收到的请求体
+func recordingBackend(t *testing.T, name string, got *[]string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		*got = append(*got, name+":"+string(body))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestModelRoutingReadsModelFromBody(t *testing.T) {
+	jsonBody := `{"model":"m1","messages":[]}`
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		want        string
+	}{
+		{"json", "application/json", jsonBody, "worker"},
+		{"json with charset", "application/json; charset=utf-8", jsonBody, "worker"},
+		{"missing content type", "", jsonBody, "worker"},
+		{"missing content type with leading space", "", "\n  " + jsonBody, "worker"},
+		{"missing content type, not json", "", "model=m1", "default"},
+		{"multipart upload", "multipart/form-data; boundary=x", jsonBody, "default"},
+		{"unknown model", "application/json", `{"model":"other"}`, "default"},
+		{"invalid json", "application/json", `{"model":`, "default"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			worker := recordingBackend(t, "worker", &got)
+			fallback := recordingBackend(t, "default", &got)
+			fallbackURL, _ := url.Parse(fallback.URL)
+			gateway := WithModelRouting(NewBaseGatewayWithTarget(fallbackURL), map[string]string{"m1": worker.URL})
+
+			req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			rec := httptest.NewRecorder()
+			gateway.HandleRequest(rec, req)
+
+			// 请求体无论是否被解析都应原样转发
+			if want := tt.want + ":" + tt.body; len(got) != 1 || got[0] != want {
+				t.Errorf("backends received %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestModelRoutingRestoresBodyAfterReadError(t *testing.T) {
+	var got []string
+	worker := recordingBackend(t, "worker", &got)
+	fallback := recordingBackend(t, "default", &got)
+	fallbackURL, _ := url.Parse(fallback.URL)
+	gateway := WithBodyLimit(
+		WithModelRouting(NewBaseGatewayWithOptions(fallbackURL, ProxyOptions{}), map[string]string{"m1": worker.URL}), 16)
+
+	// 分块传输(没有Content-Length)，超限在读取请求体时才发现
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions",
+		io.NopCloser(strings.NewReader(`{"model":"m1","messages":[]}`)))
+	req.ContentLength = -1
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	gateway.HandleRequest(rec, req)
+
+	if envelope := decodeEnvelope(t, rec); rec.Code != http.StatusRequestEntityTooLarge || envelope.Error.Code != apierror.CodePayloadTooLarge {
+		t.Errorf("got %d %s, want 413 %s", rec.Code, envelope.Error.Code, apierror.CodePayloadTooLarge)
+	}
+	if len(got) != 0 {
+		t.Errorf("backends received %q, want nothing", got)
+	}
+}
+
+// failingReader 返回一段数据后报错
+type failingReader struct {
+	data string
+	err  error
+}
+
+func (f *failingReader) Read(p []byte) (int, error) {
+	if f.data == "" {
+		return 0, f.err
+	}
+	n := copy(p, f.data)
+	f.data = f.data[n:]
+	return n, nil
+}
+
+func TestModelFromBodyKeepsReadPrefix(t *testing.T) {
+	readErr := errors.New("connection reset")
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions",
+		io.NopCloser(&failingReader{data: `{"model":"m1"`, err: readErr}))
+	req.Header.Set("Content-Type", "application/json")
+
+	if model := modelFromBody(req); model != "" {
+		t.Errorf("model = %q, want none after a read error", model)
+	}
+	// 后续处理器先读到已读取的部分，再得到原始错误
+	body, err := io.ReadAll(req.Body)
+	if string(body) != `{"model":"m1"` || !errors.Is(err, readErr) {
+		t.Errorf("restored body = %q, %v; want the read prefix and the original error", body, err)
+	}
+}
+
+func TestBodyLimit(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		chunked  bool
+		wantCode int
+	}{
+		{"content length within limit", "0123456789", false, http.StatusOK},
+		{"content length over limit", "0123456789abcdef!", false, http.StatusRequestEntityTooLarge},
+		{"chunked within limit", "0123456789abcdef", true, http.StatusOK},
+		{"chunked over limit", "0123456789abcdef!", true, http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var received []string
+			backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, err := io.ReadAll(r.Body)
+				if err == nil {
+					received = append(received, string(body))
+				}
+			}))
+			defer backend.Close()
+			target, _ := url.Parse(backend.URL)
+			gateway := WithBodyLimit(NewBaseGatewayWithOptions(target, ProxyOptions{}), 16)
+
+			// 分块传输的请求没有Content-Length，只能在读取时由MaxBytesReader限制
+			var body io.Reader = strings.NewReader(tt.body)
+			if tt.chunked {
+				body = io.NopCloser(body)
+			}
+			req := httptest.NewRequest(http.MethodPost, "/upload", body)
+			if tt.chunked {
+				req.ContentLength = -1
+			}
+			rec := httptest.NewRecorder()
+			gateway.HandleRequest(rec, req)
+
+			if rec.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantCode, rec.Body.String())
+			}
+			if tt.wantCode == http.StatusOK {
+				if len(received) != 1 || received[0] != tt.body {
+					t.Errorf("backend received %q, want %q", received, tt.body)
+				}
+				return
+			}
+			if envelope := decodeEnvelope(t, rec); envelope.Error.Code != apierror.CodePayloadTooLarge {
+				t.Errorf("error code = %s, want %s", envelope.Error.Code, apierror.CodePayloadTooLarge)
+			}
+			if len(received) != 0 {
+				t.Errorf("backend received a complete body %q", received)
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package gateway
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// metricsRegistry 网关独立的指标注册表
+var metricsRegistry = prometheus.NewRegistry()
+
+var (
+	// requestsTotal 按状态码类别统计的请求数
+	requestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "gateway",
+		Name:      "requests_total",
+		Help:      "Requests proxied by the gateway, by response status class.",
+	}, []string{"status"})
+
+	// requestDuration 请求处理耗时
+	requestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "gateway",
+		Name:      "request_duration_seconds",
+		Help:      "Gateway request latency in seconds, by response status class.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"status"})
+)
+
+func init() {
+	metricsRegistry.MustRegister(requestsTotal, requestDuration)
+}
+
+// MetricsHandler 返回暴露网关指标的HTTP处理器
+func MetricsHandler() http.Handler {
+	return promhttp.HandlerFor(metricsRegistry, promhttp.HandlerOpts{})
+}
@@ -0,0 +1,74 @@
+package gateway
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"ai-gatway/pkg/tracing"
+)
+
+// ProxyPool 按目标缓存反向代理，指向同一后端主机的代理共享一个连接池。
+// 路由装饰器按请求选择目标时使用，避免每个请求都新建连接。
+type ProxyPool struct {
+	opts       ProxyOptions
+	tlsConfigs map[string]*tls.Config
+
+	mu         sync.Mutex
+	transports map[string]http.RoundTripper
+	proxies    map[string]*BaseGateway
+}
+
+// NewProxyPool 创建代理池。tlsConfigs按后端host:port覆盖opts.TLSConfig，
+// 未匹配的https目标使用opts.TLSConfig。
+func NewProxyPool(opts ProxyOptions, tlsConfigs map[string]*tls.Config) *ProxyPool {
+	return &ProxyPool{
+		opts:       opts,
+		tlsConfigs: tlsConfigs,
+		transports: make(map[string]http.RoundTripper),
+		proxies:    make(map[string]*BaseGateway),
+	}
+}
+
+// defaultProxyPool 未指定代理池的路由装饰器共用的代理池
+var defaultProxyPool = NewProxyPool(ProxyOptions{}, nil)
+
+// Get 返回指向target的代理，flushInterval为-1时每次写入后立即刷新(用于流式路由)
+func (p *ProxyPool) Get(target *url.URL, flushInterval time.Duration) *BaseGateway {
+	key := fmt.Sprintf("%s|%d", target.String(), flushInterval)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if proxy, ok := p.proxies[key]; ok {
+		return proxy
+	}
+
+	transport, ok := p.transports[target.Host]
+	if !ok {
+		opts := p.opts
+		if tlsConfig, ok := p.tlsConfigs[target.Host]; ok {
+			opts.TLSConfig = tlsConfig
+		}
+		transport = tracing.Transport(newProxyTransport(opts))
+		p.transports[target.Host] = transport
+	}
+
+	opts := p.opts
+	opts.FlushInterval = flushInterval
+	proxy := newBaseGateway(target, transport, opts)
+	p.proxies[key] = proxy
+	return proxy
+}
+
+// GetURL 解析目标URL并返回对应的代理
+func (p *ProxyPool) GetURL(targetURL string, flushInterval time.Duration) (*BaseGateway, error) {
+	target, err := url.Parse(targetURL)
+	if err != nil {
+		return nil, err
+	}
+	return p.Get(target, flushInterval), nil
+}
@@ -0,0 +1,79 @@
+package gateway
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+)
+
+func TestProxyPoolReusesProxyPerTarget(t *testing.T) {
+	pool := NewProxyPool(ProxyOptions{}, nil)
+	a, _ := url.Parse("http://backend:8080/a")
+	b, _ := url.Parse("http://backend:8080/a")
+
+	if pool.Get(a, 0) != pool.Get(b, 0) {
+		t.Error("same target returned different proxies")
+	}
+	if pool.Get(a, 0) == pool.Get(a, -1) {
+		t.Error("streaming and buffered proxies must differ")
+	}
+}
+
+func TestRoutingReusesConnections(t *testing.T) {
+	var conns atomic.Int32
+	backend := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	backend.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			conns.Add(1)
+		}
+	}
+	backend.Start()
+	defer backend.Close()
+
+	router := WithRoutingPool(NewBaseGateway(), map[string]string{"/api": backend.URL}, NewProxyPool(ProxyOptions{}, nil))
+	for i := 0; i < 10; i++ {
+		rec := httptest.NewRecorder()
+		router.HandleRequest(rec, httptest.NewRequest(http.MethodGet, "/api/items", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("request %d: status %d", i, rec.Code)
+		}
+	}
+	if n := conns.Load(); n != 1 {
+		t.Errorf("backend saw %d connections for 10 sequential requests, want 1", n)
+	}
+}
+
+func TestModelRoutingUsesPerTargetTLS(t *testing.T) {
+	worker := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer worker.Close()
+	workerURL, _ := url.Parse(worker.URL)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(worker.Certificate())
+
+	tests := []struct {
+		name       string
+		tlsConfigs map[string]*tls.Config
+		wantStatus int
+	}{
+		{"untrusted certificate", nil, http.StatusBadGateway},
+		{"per-target CA", map[string]*tls.Config{workerURL.Host: {RootCAs: roots}}, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pool := NewProxyPool(ProxyOptions{}, tt.tlsConfigs)
+			router := WithModelRoutingPool(NewBaseGateway(), map[string]string{"m": worker.URL}, pool)
+
+			rec := httptest.NewRecorder()
+			router.HandleRequest(rec, httptest.NewRequest(http.MethodGet, "/v1/chat/completions?model=m", nil))
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+		})
+	}
+}
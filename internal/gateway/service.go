@@ -1,10 +1,21 @@
 package gateway
 
 import (
-	"log"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
 	"net/http"
 	"net/http/httputil"
 	"net/url"
+	"time"
+
+	"ai-gatway/pkg/apierror"
+	"ai-gatway/pkg/tracing"
+	"ai-gatway/pkg/utils"
+
+	"github.com/sirupsen/logrus"
 )
 
 // Gateway 定义网关接口
@@ -26,9 +37,100 @@ func NewBaseGateway() *BaseGateway {
 
 // NewBaseGatewayWithTarget 创建基础网关服务(指定目标URL)
 func NewBaseGatewayWithTarget(target *url.URL) *BaseGateway {
+	return NewBaseGatewayWithTLS(target, nil)
+}
+
+// ProxyOptions 反向代理的传输和缓冲配置
+type ProxyOptions struct {
+	// TLSConfig 访问后端的TLS客户端配置，nil表示默认配置
+	TLSConfig *tls.Config
+	// DialTimeout 建立后端连接的超时时间
+	DialTimeout time.Duration
+	// ResponseHeaderTimeout 等待后端响应头的超时时间
+	ResponseHeaderTimeout time.Duration
+	// FlushInterval 响应刷新间隔，-1表示每次写入后立即刷新(用于流式路由)
+	FlushInterval time.Duration
+	// Logger 记录代理错误的日志记录器，nil时使用logrus默认记录器
+	Logger logrus.FieldLogger
+}
+
+// NewBaseGatewayWithTLS 创建基础网关服务，使用指定的TLS客户端配置访问后端(nil表示默认配置)
+func NewBaseGatewayWithTLS(target *url.URL, tlsConfig *tls.Config) *BaseGateway {
+	return NewBaseGatewayWithOptions(target, ProxyOptions{TLSConfig: tlsConfig})
+}
+
+// NewBaseGatewayWithOptions 创建基础网关服务(指定代理选项)，每次调用都会创建新的连接池，
+// 需要按目标复用连接时使用ProxyPool
+func NewBaseGatewayWithOptions(target *url.URL, opts ProxyOptions) *BaseGateway {
+	return newBaseGateway(target, tracing.Transport(newProxyTransport(opts)), opts)
+}
+
+// newProxyTransport 按代理选项创建传输层
+func newProxyTransport(opts ProxyOptions) *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if opts.TLSConfig != nil {
+		transport.TLSClientConfig = opts.TLSConfig
+	}
+	if opts.DialTimeout > 0 {
+		transport.DialContext = (&net.Dialer{
+			Timeout:   opts.DialTimeout,
+			KeepAlive: 30 * time.Second,
+		}).DialContext
+	}
+	if opts.ResponseHeaderTimeout > 0 {
+		transport.ResponseHeaderTimeout = opts.ResponseHeaderTimeout
+	}
+	return transport
+}
+
+// newBaseGateway 使用指定传输层创建反向代理
+func newBaseGateway(target *url.URL, transport http.RoundTripper, opts ProxyOptions) *BaseGateway {
+	proxy := httputil.NewSingleHostReverseProxy(target)
+	// 向后端传播追踪上下文
+	proxy.Transport = transport
+	proxy.FlushInterval = opts.FlushInterval
+	proxy.ErrorHandler = newProxyErrorHandler(opts.Logger)
+
 	return &BaseGateway{
-		proxy: httputil.NewSingleHostReverseProxy(target),
+		proxy: proxy,
+	}
+}
+
+// newProxyErrorHandler 创建代理错误处理器，后端不可用时返回结构化的错误响应，替代默认的空502
+func newProxyErrorHandler(logger logrus.FieldLogger) func(http.ResponseWriter, *http.Request, error) {
+	if logger == nil {
+		logger = logrus.StandardLogger()
+	}
+	return func(w http.ResponseWriter, r *http.Request, err error) {
+		logger.WithFields(logrus.Fields{
+			"path":       r.URL.Path,
+			"method":     r.Method,
+			"request_id": r.Header.Get(apierror.RequestIDHeader),
+		}).Warnf("Proxy error: %v", err)
+		writeProxyError(w, r, err)
+	}
+}
+
+// writeProxyError 根据代理错误类型写入错误响应
+func writeProxyError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, context.Canceled) {
+		// 客户端已断开，无需响应
+		return
+	}
+
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		apierror.WriteError(w, r, http.StatusRequestEntityTooLarge, apierror.CodePayloadTooLarge,
+			fmt.Sprintf("Request body exceeds %d bytes", maxBytesErr.Limit))
+		return
+	}
+
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		apierror.WriteError(w, r, http.StatusGatewayTimeout, apierror.CodeUpstreamUnavailable, "Upstream service timed out")
+		return
 	}
+	apierror.WriteError(w, r, http.StatusBadGateway, apierror.CodeUpstreamUnavailable, "Upstream service unavailable")
 }
 
 // HandleRequest 处理网关请求
@@ -39,20 +141,80 @@ func (g *BaseGateway) HandleRequest(w http.ResponseWriter, r *http.Request) {
 // loggingDecorator 日志装饰器
 type loggingDecorator struct {
 	gateway Gateway
+	logger  logrus.FieldLogger
 }
 
 // WithLogging 添加日志功能的装饰器
-func WithLogging(gateway Gateway) Gateway {
-	return &loggingDecorator{gateway: gateway}
+func WithLogging(gateway Gateway, logger logrus.FieldLogger) Gateway {
+	return &loggingDecorator{gateway: gateway, logger: logger}
 }
 
 func (d *loggingDecorator) HandleRequest(w http.ResponseWriter, r *http.Request) {
+	// 生成或沿用请求ID，转发给后端并在响应中回显
+	requestID := r.Header.Get(apierror.RequestIDHeader)
+	if requestID == "" {
+		requestID = utils.NewRequestID()
+		r.Header.Set(apierror.RequestIDHeader, requestID)
+	}
+	w.Header().Set(apierror.RequestIDHeader, requestID)
+
 	// 记录请求信息
-	log.Printf("Incoming request: %s %s from %s", r.Method, r.URL.Path, r.RemoteAddr)
+	entry := d.logger.WithFields(logrus.Fields{
+		"path":       r.URL.Path,
+		"method":     r.Method,
+		"request_id": requestID,
+	})
+	entry.WithField("remote_addr", r.RemoteAddr).Debug("Incoming request")
+
+	// 调用实际处理，记录状态码、字节数和耗时
+	start := time.Now()
+	recorder := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+	d.gateway.HandleRequest(recorder, r)
+	elapsed := time.Since(start)
 
-	// 调用实际处理
-	d.gateway.HandleRequest(w, r)
+	class := fmt.Sprintf("%dxx", recorder.status/100)
+	requestsTotal.WithLabelValues(class).Inc()
+	requestDuration.WithLabelValues(class).Observe(elapsed.Seconds())
 
 	// 记录响应信息
-	log.Printf("Completed request: %s %s", r.Method, r.URL.Path)
+	entry.WithFields(logrus.Fields{
+		"status":   recorder.status,
+		"bytes":    recorder.bytes,
+		"duration": elapsed.String(),
+	}).Info("Completed request")
+}
+
+// responseRecorder 记录后端写入的状态码和响应字节数
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	bytes       int64
+	wroteHeader bool
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	n, err := r.ResponseWriter.Write(b)
+	r.bytes += int64(n)
+	return n, err
+}
+
+// Flush 透传流式响应的刷新
+func (r *responseRecorder) Flush() {
+	if flusher, ok := r.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap 供http.ResponseController访问底层ResponseWriter
+func (r *responseRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
 }
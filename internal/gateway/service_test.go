@@ -0,0 +1,295 @@
+package gateway
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"ai-gatway/pkg/apierror"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+// scrapeMetrics 抓取网关指标并按序列名(含标签)返回样本值
+func scrapeMetrics(t *testing.T) map[string]float64 {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	MetricsHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	scrape, _ := io.ReadAll(rec.Body)
+
+	samples := make(map[string]float64)
+	for _, line := range strings.Split(string(scrape), "\n") {
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		i := strings.LastIndex(line, " ")
+		value, err := strconv.ParseFloat(line[i+1:], 64)
+		if err != nil {
+			t.Fatalf("malformed sample %q: %v", line, err)
+		}
+		samples[line[:i]] = value
+	}
+	return samples
+}
+
+func TestLoggingRecordsResponse(t *testing.T) {
+	tests := []struct {
+		name      string
+		handler   http.HandlerFunc
+		wantCode  int
+		wantBytes int64
+		wantClass string
+		minTime   time.Duration
+	}{
+		{
+			name:      "implicit 200",
+			handler:   func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("hello")) },
+			wantCode:  http.StatusOK,
+			wantBytes: 5,
+			wantClass: "2xx",
+		},
+		{
+			name: "slow backend",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				time.Sleep(30 * time.Millisecond)
+				w.WriteHeader(http.StatusCreated)
+				w.Write([]byte("{}"))
+			},
+			wantCode:  http.StatusCreated,
+			wantBytes: 2,
+			wantClass: "2xx",
+			minTime:   30 * time.Millisecond,
+		},
+		{
+			name: "not found",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusNotFound)
+				w.Write([]byte("missing"))
+			},
+			wantCode:  http.StatusNotFound,
+			wantBytes: 7,
+			wantClass: "4xx",
+		},
+		{
+			name: "backend error",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			},
+			wantCode:  http.StatusServiceUnavailable,
+			wantClass: "5xx",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := httptest.NewServer(tt.handler)
+			defer backend.Close()
+			target, _ := url.Parse(backend.URL)
+
+			logger, logs := test.NewNullLogger()
+			logger.SetLevel(logrus.DebugLevel)
+			gateway := WithLogging(NewBaseGatewayWithTarget(target), logger)
+
+			// 指标注册表是全局的，只比较请求前后的差值
+			before := scrapeMetrics(t)
+			rec := httptest.NewRecorder()
+			gateway.HandleRequest(rec, httptest.NewRequest(http.MethodGet, "/v1/models", nil))
+			after := scrapeMetrics(t)
+
+			if rec.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantCode)
+			}
+
+			entry := logs.LastEntry()
+			if entry == nil || entry.Message != "Completed request" {
+				t.Fatalf("last log entry = %v, want the completed request", entry)
+			}
+			if entry.Data["status"] != tt.wantCode || entry.Data["bytes"] != tt.wantBytes {
+				t.Errorf("logged status %v and bytes %v, want %d and %d", entry.Data["status"], entry.Data["bytes"], tt.wantCode, tt.wantBytes)
+			}
+			duration, err := time.ParseDuration(entry.Data["duration"].(string))
+			if err != nil || duration < tt.minTime {
+				t.Errorf("logged duration %v (%v), want at least %v", entry.Data["duration"], err, tt.minTime)
+			}
+			if entry.Data["path"] != "/v1/models" || entry.Data["method"] != http.MethodGet || entry.Data["request_id"] == "" {
+				t.Errorf("missing request fields: %v", entry.Data)
+			}
+
+			requests := `gateway_requests_total{status="` + tt.wantClass + `"}`
+			if got := after[requests] - before[requests]; got != 1 {
+				t.Errorf("%s increased by %v, want 1", requests, got)
+			}
+			count := `gateway_request_duration_seconds_count{status="` + tt.wantClass + `"}`
+			if got := after[count] - before[count]; got != 1 {
+				t.Errorf("%s increased by %v, want 1", count, got)
+			}
+			sum := `gateway_request_duration_seconds_sum{status="` + tt.wantClass + `"}`
+			if got := after[sum] - before[sum]; got < tt.minTime.Seconds() {
+				t.Errorf("%s increased by %v, want at least %v", sum, got, tt.minTime.Seconds())
+			}
+		})
+	}
+}
+
+func TestProxyUpstreamTimeout(t *testing.T) {
+	release := make(chan struct{})
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			<-release
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer backend.Close()
+	defer close(release)
+	target, _ := url.Parse(backend.URL)
+
+	logger, _ := test.NewNullLogger()
+	gateway := WithLogging(NewBaseGatewayWithOptions(target, ProxyOptions{
+		ResponseHeaderTimeout: 50 * time.Millisecond,
+		Logger:                logger,
+	}), logger)
+
+	tests := []struct {
+		path     string
+		wantCode int
+	}{
+		{"/fast", http.StatusOK},
+		{"/slow", http.StatusGatewayTimeout},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			gateway.HandleRequest(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if rec.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantCode)
+			}
+			if tt.wantCode == http.StatusOK {
+				return
+			}
+			envelope := decodeEnvelope(t, rec)
+			if envelope.Error.Code != apierror.CodeUpstreamUnavailable || envelope.Error.Message != "Upstream service timed out" {
+				t.Errorf("envelope = %+v, want the upstream timeout error", envelope.Error)
+			}
+		})
+	}
+}
+
+func TestProxyFlushInterval(t *testing.T) {
+	const first, second = "data: 1\n", "data: 2\n"
+	tests := []struct {
+		name          string
+		flushInterval time.Duration
+		wantEarly     bool
+	}{
+		{"streaming route flushes every write", -1, true},
+		{"buffered route", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 后端声明了Content-Length，代理不会自动按流式响应刷新
+			release := make(chan struct{})
+			backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Length", strconv.Itoa(len(first+second)))
+				w.Write([]byte(first))
+				w.(http.Flusher).Flush()
+				<-release
+				w.Write([]byte(second))
+			}))
+			defer backend.Close()
+			target, _ := url.Parse(backend.URL)
+
+			proxy := NewBaseGatewayWithOptions(target, ProxyOptions{FlushInterval: tt.flushInterval})
+			front := httptest.NewServer(http.HandlerFunc(proxy.HandleRequest))
+			defer front.Close()
+
+			// 在后端写出第二段之前读取第一段(缓冲的路由连响应头都不会提前发出)
+			got := make(chan string, 1)
+			go func() {
+				resp, err := http.Get(front.URL)
+				if err != nil {
+					got <- err.Error()
+					return
+				}
+				defer resp.Body.Close()
+				buf := make([]byte, len(first))
+				n, _ := io.ReadFull(resp.Body, buf)
+				got <- string(buf[:n])
+				io.Copy(io.Discard, resp.Body)
+			}()
+			select {
+			case chunk := <-got:
+				if !tt.wantEarly {
+					t.Errorf("buffered route delivered %q before the backend finished", chunk)
+				} else if chunk != first {
+					t.Errorf("first chunk = %q, want %q", chunk, first)
+				}
+			case <-time.After(300 * time.Millisecond):
+				if tt.wantEarly {
+					t.Error("streaming route did not deliver the first chunk before the backend finished")
+				}
+			}
+			close(release)
+
+			// 缓冲的路由在后端完成后才一次性送达
+			if !tt.wantEarly {
+				if chunk := <-got; chunk != first {
+					t.Errorf("first chunk = %q after the backend finished, want %q", chunk, first)
+				}
+			}
+		})
+	}
+}
+
+func TestLoggingPropagatesRequestID(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// 回显后端收到的请求ID
+		w.Write([]byte(r.Header.Get(apierror.RequestIDHeader)))
+	}))
+	defer backend.Close()
+	backendURL, _ := url.Parse(backend.URL)
+	dead := httptest.NewServer(http.NotFoundHandler())
+	dead.Close()
+	deadURL, _ := url.Parse(dead.URL)
+
+	logger, _ := test.NewNullLogger()
+	tests := []struct {
+		name     string
+		target   *url.URL
+		incoming string
+	}{
+		{"generated", backendURL, ""},
+		{"forwarded", backendURL, "client-id-1"},
+		{"generated in error envelope", deadURL, ""},
+		{"forwarded in error envelope", deadURL, "client-id-2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gateway := WithLogging(NewBaseGatewayWithOptions(tt.target, ProxyOptions{Logger: logger}), logger)
+			req := httptest.NewRequest(http.MethodGet, "/v1/models", nil)
+			if tt.incoming != "" {
+				req.Header.Set(apierror.RequestIDHeader, tt.incoming)
+			}
+			rec := httptest.NewRecorder()
+			gateway.HandleRequest(rec, req)
+
+			requestID := rec.Header().Get(apierror.RequestIDHeader)
+			if requestID == "" || (tt.incoming != "" && requestID != tt.incoming) {
+				t.Fatalf("response request ID = %q, incoming %q", requestID, tt.incoming)
+			}
+			if tt.target == backendURL {
+				if got := rec.Body.String(); got != requestID {
+					t.Errorf("backend received request ID %q, want %q", got, requestID)
+				}
+				return
+			}
+			if envelope := decodeEnvelope(t, rec); envelope.Error.RequestID != requestID {
+				t.Errorf("envelope request_id = %q, want %q", envelope.Error.RequestID, requestID)
+			}
+		})
+	}
+}
@@ -0,0 +1,176 @@
+package gateway
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testCert 测试用证书及其PEM文件路径
+type testCert struct {
+	cert     *x509.Certificate
+	key      *ecdsa.PrivateKey
+	certFile string
+	keyFile  string
+}
+
+// newTestCert 生成证书并写入临时目录，parent为nil时生成自签名CA
+func newTestCert(t *testing.T, name string, parent *testCert) *testCert {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	issuer, signer := template, key
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage |= x509.KeyUsageCertSign
+	} else {
+		issuer, signer = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, issuer, &key.PublicKey, signer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	c := &testCert{
+		cert:     cert,
+		key:      key,
+		certFile: filepath.Join(dir, name+".crt"),
+		keyFile:  filepath.Join(dir, name+".key"),
+	}
+	writeFile(t, c.certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+	writeFile(t, c.keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}))
+	return c
+}
+
+func writeFile(t *testing.T, path string, data []byte) {
+	t.Helper()
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadClientTLSConfig(t *testing.T) {
+	ca := newTestCert(t, "ca", nil)
+	client := newTestCert(t, "gateway", ca)
+	other := newTestCert(t, "other", ca)
+	dir := t.TempDir()
+	missing := filepath.Join(dir, "missing.pem")
+	garbage := filepath.Join(dir, "garbage.pem")
+	writeFile(t, garbage, []byte("not a pem file"))
+
+	tests := []struct {
+		name      string
+		opts      TLSOptions
+		wantErr   string
+		wantCerts int
+	}{
+		{name: "no files", opts: TLSOptions{ServerName: "backend"}},
+		{name: "client certificate and CA", opts: TLSOptions{CertFile: client.certFile, KeyFile: client.keyFile, CAFile: ca.certFile}, wantCerts: 1},
+		{name: "cert without key", opts: TLSOptions{CertFile: client.certFile}, wantErr: "both cert_file and key_file are required"},
+		{name: "key without cert", opts: TLSOptions{KeyFile: client.keyFile}, wantErr: "both cert_file and key_file are required"},
+		{name: "missing cert file", opts: TLSOptions{CertFile: missing, KeyFile: client.keyFile}, wantErr: "failed to load client certificate"},
+		{name: "bad cert file", opts: TLSOptions{CertFile: garbage, KeyFile: client.keyFile}, wantErr: "failed to load client certificate"},
+		{name: "key does not match cert", opts: TLSOptions{CertFile: client.certFile, KeyFile: other.keyFile}, wantErr: "failed to load client certificate"},
+		{name: "missing CA file", opts: TLSOptions{CAFile: missing}, wantErr: "failed to read CA bundle"},
+		{name: "CA file without certificates", opts: TLSOptions{CAFile: garbage}, wantErr: "no valid certificates found in CA bundle"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := LoadClientTLSConfig(tt.opts)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want it to mention %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(cfg.Certificates) != tt.wantCerts || cfg.ServerName != tt.opts.ServerName || cfg.MinVersion != tls.VersionTLS12 {
+				t.Errorf("unexpected config: %d certificates, server name %q", len(cfg.Certificates), cfg.ServerName)
+			}
+		})
+	}
+}
+
+func TestProxyPresentsClientCertificate(t *testing.T) {
+	ca := newTestCert(t, "ca", nil)
+	client := newTestCert(t, "gateway", ca)
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(ca.cert)
+
+	// 后端要求并校验客户端证书，回显证书的CN
+	backend := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.TLS.PeerCertificates[0].Subject.CommonName))
+	}))
+	backend.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs}
+	backend.StartTLS()
+	defer backend.Close()
+	backendURL, _ := url.Parse(backend.URL)
+
+	serverCA := filepath.Join(t.TempDir(), "server-ca.pem")
+	writeFile(t, serverCA, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: backend.Certificate().Raw}))
+
+	tests := []struct {
+		name       string
+		opts       TLSOptions
+		perTarget  bool
+		wantStatus int
+		wantBody   string
+	}{
+		{"with client certificate", TLSOptions{CertFile: client.certFile, KeyFile: client.keyFile, CAFile: serverCA}, false, http.StatusOK, "gateway"},
+		{"per-target client certificate", TLSOptions{CertFile: client.certFile, KeyFile: client.keyFile, CAFile: serverCA}, true, http.StatusOK, "gateway"},
+		{"without client certificate", TLSOptions{CAFile: serverCA}, false, http.StatusBadGateway, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tlsConfig, err := LoadClientTLSConfig(tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var proxy Gateway = NewBaseGatewayWithOptions(backendURL, ProxyOptions{TLSConfig: tlsConfig})
+			if tt.perTarget {
+				proxy = NewProxyPool(ProxyOptions{}, map[string]*tls.Config{backendURL.Host: tlsConfig}).Get(backendURL, 0)
+			}
+
+			rec := httptest.NewRecorder()
+			proxy.HandleRequest(rec, httptest.NewRequest(http.MethodGet, "/v1/models", nil))
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if tt.wantBody != "" && rec.Body.String() != tt.wantBody {
+				t.Errorf("backend saw client certificate %q, want %q", rec.Body.String(), tt.wantBody)
+			}
+		})
+	}
+}
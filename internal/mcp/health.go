@@ -0,0 +1,229 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"ai-gatway/pkg/httpx"
+)
+
+// 健康检查默认参数
+const (
+	defaultHealthTimeout  = 2 * time.Second
+	defaultHealthCacheTTL = 5 * time.Second
+)
+
+// WorkerHealth 表示单个工作节点的健康状态
+type WorkerHealth struct {
+	Name      string    `json:"name"`
+	URL       string    `json:"url"`
+	Model     string    `json:"model"`
+	Healthy   bool      `json:"healthy"`
+	Error     string    `json:"error,omitempty"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// HealthReport 表示MCP服务的整体健康状态
+type HealthReport struct {
+	Status         string         `json:"status"`
+	HealthyWorkers int            `json:"healthy_workers"`
+	TotalWorkers   int            `json:"total_workers"`
+	Workers        []WorkerHealth `json:"workers"`
+}
+
+// HealthChecker 探测工作节点健康状态并缓存结果
+type HealthChecker struct {
+	workers  []ModelWorker
+	clients  map[string]*httpx.Client
+	cacheTTL time.Duration
+
+	mu        sync.Mutex
+	report    *HealthReport
+	checkedAt time.Time
+	// inflight 进行中的探测，完成时关闭；并发的Check等待同一次探测
+	inflight chan struct{}
+}
+
+// NewHealthChecker 创建健康检查器，timeout为单次探测超时，cacheTTL为结果缓存时间
+func NewHealthChecker(workers []ModelWorker, timeout, cacheTTL time.Duration) *HealthChecker {
+	return newHealthChecker(workers, nil, timeout, cacheTTL)
+}
+
+// NewHealthChecker 创建探测模型服务工作节点的健康检查器，探测复用SetClientConfig创建的连接池
+func (s *ModelService) NewHealthChecker(timeout, cacheTTL time.Duration) *HealthChecker {
+	return newHealthChecker(s.Workers, s.transports, timeout, cacheTTL)
+}
+
+// newHealthChecker 按工作节点名称选择transport创建探测客户端，未指定时使用独立的连接池
+func newHealthChecker(workers []ModelWorker, transports map[string]http.RoundTripper, timeout, cacheTTL time.Duration) *HealthChecker {
+	if timeout <= 0 {
+		timeout = defaultHealthTimeout
+	}
+	if cacheTTL <= 0 {
+		cacheTTL = defaultHealthCacheTTL
+	}
+
+	fallback := httpx.New(httpx.Config{Timeout: timeout})
+	clients := make(map[string]*httpx.Client, len(workers))
+	for _, worker := range workers {
+		clients[worker.Name] = fallback
+		if transport := transports[worker.Name]; transport != nil {
+			clients[worker.Name] = httpx.New(httpx.Config{Timeout: timeout, Transport: transport})
+		}
+	}
+	return &HealthChecker{
+		workers:  workers,
+		clients:  clients,
+		cacheTTL: cacheTTL,
+	}
+}
+
+// Check 返回健康报告，缓存未过期时直接返回缓存结果。
+// 探测在锁外进行，并发调用共享同一次探测。
+func (h *HealthChecker) Check(ctx context.Context) HealthReport {
+	h.mu.Lock()
+	if h.report != nil && time.Since(h.checkedAt) < h.cacheTTL {
+		report := *h.report
+		h.mu.Unlock()
+		return report
+	}
+
+	done := h.inflight
+	if done == nil {
+		done = make(chan struct{})
+		h.inflight = done
+		h.mu.Unlock()
+
+		// 探测结果被其他调用共享，不随当前请求取消
+		report := h.probeAll(context.WithoutCancel(ctx))
+		h.store(report)
+		h.mu.Lock()
+		h.inflight = nil
+		h.mu.Unlock()
+		close(done)
+		return report
+	}
+	h.mu.Unlock()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.report == nil {
+		return HealthReport{Status: "unavailable", TotalWorkers: len(h.workers)}
+	}
+	return *h.report
+}
+
+// store 保存最新的健康报告
+func (h *HealthChecker) store(report HealthReport) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.report = &report
+	h.checkedAt = time.Now()
+}
+
+// Start 在后台按固定间隔刷新健康状态，直到ctx被取消
+func (h *HealthChecker) Start(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				h.store(h.probeAll(ctx))
+			}
+		}
+	}()
+}
+
+// probeAll 并发探测所有工作节点
+func (h *HealthChecker) probeAll(ctx context.Context) HealthReport {
+	results := make([]WorkerHealth, len(h.workers))
+
+	var wg sync.WaitGroup
+	for i, worker := range h.workers {
+		wg.Add(1)
+		go func(i int, worker ModelWorker) {
+			defer wg.Done()
+			results[i] = h.probe(ctx, worker)
+		}(i, worker)
+	}
+	wg.Wait()
+
+	report := HealthReport{
+		TotalWorkers: len(results),
+		Workers:      results,
+	}
+	for _, result := range results {
+		up := 0.0
+		if result.Healthy {
+			report.HealthyWorkers++
+			up = 1
+		}
+		workerUp.WithLabelValues(result.Name, result.Model).Set(up)
+	}
+	healthyWorkers.Set(float64(report.HealthyWorkers))
+
+	switch {
+	case report.HealthyWorkers == 0:
+		report.Status = "unavailable"
+	case report.HealthyWorkers < report.TotalWorkers:
+		report.Status = "degraded"
+	default:
+		report.Status = "ok"
+	}
+
+	return report
+}
+
+// probe 探测单个工作节点的/health端点
+func (h *HealthChecker) probe(ctx context.Context, worker ModelWorker) WorkerHealth {
+	result := WorkerHealth{
+		Name:      worker.Name,
+		URL:       worker.URL,
+		Model:     worker.Model,
+		CheckedAt: time.Now(),
+	}
+
+	resp, err := h.clients[worker.Name].Get(ctx, worker.URL+"/health")
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		result.Error = fmt.Sprintf("unexpected status %d", resp.StatusCode)
+		return result
+	}
+
+	result.Healthy = true
+	return result
+}
+
+// HandleHealth 处理健康检查请求，没有健康工作节点时返回503
+func (s *ModelService) HandleHealth(w http.ResponseWriter, r *http.Request) {
+	report := s.Health.Check(r.Context())
+
+	status := http.StatusOK
+	if report.HealthyWorkers == 0 {
+		status = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(report)
+}
@@ -0,0 +1,175 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newStubWorker 启动返回指定状态码的工作节点，并记录/health探测次数
+func newStubWorker(t *testing.T, status int, delay time.Duration) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var probes atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/health" {
+			probes.Add(1)
+		}
+		time.Sleep(delay)
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+	return server, &probes
+}
+
+// deadWorkerURL 返回一个已关闭的地址，连接会被拒绝
+func deadWorkerURL(t *testing.T) string {
+	t.Helper()
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+	return server.URL
+}
+
+func TestHandleHealth(t *testing.T) {
+	healthy, _ := newStubWorker(t, http.StatusOK, 0)
+	failing, _ := newStubWorker(t, http.StatusInternalServerError, 0)
+	dead := deadWorkerURL(t)
+
+	tests := []struct {
+		name        string
+		workers     []ModelWorker
+		wantCode    int
+		wantStatus  string
+		wantHealthy int
+	}{
+		{
+			name:        "all healthy",
+			workers:     []ModelWorker{{Name: "a", URL: healthy.URL, Model: "m"}},
+			wantCode:    http.StatusOK,
+			wantStatus:  "ok",
+			wantHealthy: 1,
+		},
+		{
+			name: "mixed",
+			workers: []ModelWorker{
+				{Name: "a", URL: healthy.URL, Model: "m"},
+				{Name: "b", URL: dead, Model: "m"},
+				{Name: "c", URL: failing.URL, Model: "m"},
+			},
+			wantCode:    http.StatusOK,
+			wantStatus:  "degraded",
+			wantHealthy: 1,
+		},
+		{
+			name: "all dead",
+			workers: []ModelWorker{
+				{Name: "b", URL: dead, Model: "m"},
+				{Name: "c", URL: failing.URL, Model: "m"},
+			},
+			wantCode:    http.StatusServiceUnavailable,
+			wantStatus:  "unavailable",
+			wantHealthy: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewModelService(tt.workers, map[string]ModelInfo{"m": {ID: "m"}})
+			s.Health = NewHealthChecker(tt.workers, time.Second, time.Minute)
+
+			rec := httptest.NewRecorder()
+			s.HandleHealth(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+
+			var report HealthReport
+			if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+				t.Fatal(err)
+			}
+			if rec.Code != tt.wantCode || report.Status != tt.wantStatus || report.HealthyWorkers != tt.wantHealthy {
+				t.Errorf("got %d %s %d healthy, want %d %s %d", rec.Code, report.Status, report.HealthyWorkers,
+					tt.wantCode, tt.wantStatus, tt.wantHealthy)
+			}
+			for _, w := range report.Workers {
+				if !w.Healthy && w.Error == "" {
+					t.Errorf("unhealthy worker %s has no error", w.Name)
+				}
+			}
+		})
+	}
+}
+
+func TestHealthCheckSharesConcurrentProbes(t *testing.T) {
+	worker, probes := newStubWorker(t, http.StatusOK, 100*time.Millisecond)
+	checker := NewHealthChecker([]ModelWorker{{Name: "a", URL: worker.URL, Model: "m"}}, time.Second, time.Minute)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if report := checker.Check(context.Background()); report.Status != "ok" {
+				t.Errorf("status = %s, want ok", report.Status)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := probes.Load(); n != 1 {
+		t.Errorf("worker probed %d times by concurrent checks, want 1", n)
+	}
+
+	// 缓存有效期内不再探测
+	checker.Check(context.Background())
+	if n := probes.Load(); n != 1 {
+		t.Errorf("cached check probed again, %d probes", n)
+	}
+}
+
+func TestHealthCheckWaiterHonorsContext(t *testing.T) {
+	worker, _ := newStubWorker(t, http.StatusOK, 300*time.Millisecond)
+	checker := NewHealthChecker([]ModelWorker{{Name: "a", URL: worker.URL, Model: "m"}}, time.Second, time.Minute)
+
+	go checker.Check(context.Background())
+	time.Sleep(20 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	report := checker.Check(ctx)
+	if elapsed := time.Since(start); elapsed > 200*time.Millisecond {
+		t.Errorf("waiting check took %v despite cancelled context", elapsed)
+	}
+	if report.Status != "unavailable" {
+		t.Errorf("status = %s before the first probe finished, want unavailable", report.Status)
+	}
+}
+
+func TestHealthProbeSharesWorkerConnections(t *testing.T) {
+	var conns atomic.Int32
+	worker := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{}`))
+	}))
+	worker.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			conns.Add(1)
+		}
+	}
+	worker.Start()
+	defer worker.Close()
+
+	s := NewModelService([]ModelWorker{{Name: "a", URL: worker.URL, Model: "m"}}, map[string]ModelInfo{"m": {ID: "m"}})
+	s.Health = s.NewHealthChecker(time.Second, time.Minute)
+
+	if rec := chatRequest(s, `{"model":"m","messages":[{"role":"user","content":"hi"}]}`); rec.Code != http.StatusOK {
+		t.Fatalf("status = %d", rec.Code)
+	}
+	if report := s.Health.Check(context.Background()); report.Status != "ok" {
+		t.Fatalf("status = %s, want ok", report.Status)
+	}
+	if n := conns.Load(); n != 1 {
+		t.Errorf("worker saw %d connections, want the health probe to reuse the chat connection", n)
+	}
+}
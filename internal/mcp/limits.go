@@ -0,0 +1,74 @@
+package mcp
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"ai-gatway/pkg/apierror"
+)
+
+// 默认请求/响应体大小上限
+const (
+	defaultMaxRequestBytes  = 4 << 20
+	defaultMaxResponseBytes = 32 << 20
+)
+
+// errResponseTooLarge 上游响应体超过上限
+var errResponseTooLarge = errors.New("model worker response too large")
+
+// SetBodyLimits 设置聊天请求体和上游响应体的大小上限(字节)，<=0表示不限制
+func (s *ModelService) SetBodyLimits(maxRequestBytes, maxResponseBytes int64) {
+	s.maxRequestBytes = maxRequestBytes
+	s.maxResponseBytes = maxResponseBytes
+}
+
+// writeDecodeError 写入请求体解析失败的错误响应，请求体超限时返回413
+func writeDecodeError(w http.ResponseWriter, r *http.Request, err error) {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		apierror.WriteError(w, r, http.StatusRequestEntityTooLarge, apierror.CodePayloadTooLarge,
+			fmt.Sprintf("Request body exceeds %d bytes", maxBytesErr.Limit))
+		return
+	}
+	apierror.WriteError(w, r, http.StatusBadRequest, apierror.CodeBadRequest, "Invalid request body")
+}
+
+// isEventStream 判断上游响应是否为SSE流
+func isEventStream(resp *http.Response) bool {
+	return strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream")
+}
+
+// readLimited 读取整个响应体，超过limit时返回errResponseTooLarge，limit<=0表示不限制
+func readLimited(body io.Reader, limit int64) ([]byte, error) {
+	if limit <= 0 {
+		return io.ReadAll(body)
+	}
+	data, err := io.ReadAll(io.LimitReader(body, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, errResponseTooLarge
+	}
+	return data, nil
+}
+
+// copyLimited 边读边转发响应体，超过limit时停止并返回errResponseTooLarge，limit<=0表示不限制
+func copyLimited(dst io.Writer, src io.Reader, limit int64) (int64, error) {
+	if limit <= 0 {
+		return io.Copy(dst, src)
+	}
+	n, err := io.Copy(dst, io.LimitReader(src, limit))
+	if err != nil {
+		return n, err
+	}
+	// 恰好达到上限时探测是否还有剩余数据
+	var probe [1]byte
+	if m, _ := src.Read(probe[:]); m > 0 {
+		return n, errResponseTooLarge
+	}
+	return n, nil
+}
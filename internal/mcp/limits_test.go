@@ -0,0 +1,213 @@
+package mcp
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"ai-gatway/pkg/apierror"
+
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+func TestChatBodyLimits(t *testing.T) {
+	small := `{"model":"m","messages":[{"role":"user","content":"hi"}]}`
+	large := `{"model":"m","messages":[{"role":"user","content":"` + strings.Repeat("x", 200) + `"}]}`
+	json64 := `{"id":"` + strings.Repeat("a", 64-9) + `"}`
+	sse64 := "data: " + strings.Repeat("b", 64-8) + "\n\n"
+
+	tests := []struct {
+		name        string
+		maxRequest  int64
+		maxResponse int64
+		request     string
+		contentType string
+		response    string
+		// chunked 为true时上游不设置Content-Length
+		chunked    bool
+		wantStatus int
+		wantCode   apierror.Code
+		wantAbort  bool
+		wantHits   int32
+		// wantLog 区分触发的是哪一条限制
+		wantLog string
+	}{
+		{
+			name:       "request within limit",
+			maxRequest: int64(len(small)), maxResponse: 1024,
+			request: small, contentType: "application/json", response: `{"id":"ok"}`,
+			wantStatus: http.StatusOK, wantHits: 1,
+		},
+		{
+			name:       "request body too large",
+			maxRequest: 100, maxResponse: 1024,
+			request:    large,
+			wantStatus: http.StatusRequestEntityTooLarge, wantCode: apierror.CodePayloadTooLarge,
+		},
+		{
+			name:       "response content length too large",
+			maxRequest: 1024, maxResponse: 64,
+			request: small, contentType: "application/json", response: json64 + " ",
+			wantStatus: http.StatusBadGateway, wantCode: apierror.CodeUpstreamUnavailable, wantHits: 1,
+			wantLog: "exceeds the 64 byte limit",
+		},
+		{
+			name:       "chunked response too large",
+			maxRequest: 1024, maxResponse: 64,
+			request: small, contentType: "application/json", response: json64 + " ", chunked: true,
+			wantStatus: http.StatusBadGateway, wantCode: apierror.CodeUpstreamUnavailable, wantHits: 1,
+			wantLog: "Failed to read model worker response",
+		},
+		{
+			name:       "chunked response exactly at limit",
+			maxRequest: 1024, maxResponse: 64,
+			request: small, contentType: "application/json", response: json64, chunked: true,
+			wantStatus: http.StatusOK, wantHits: 1,
+		},
+		{
+			name:       "stream exactly at limit",
+			maxRequest: 1024, maxResponse: 64,
+			request: small, contentType: "text/event-stream", response: sse64, chunked: true,
+			wantStatus: http.StatusOK, wantHits: 1,
+		},
+		{
+			name:       "stream too large",
+			maxRequest: 1024, maxResponse: 64,
+			request: small, contentType: "text/event-stream", response: sse64 + sse64, chunked: true,
+			wantStatus: http.StatusOK, wantAbort: true, wantHits: 1,
+			wantLog: "stream exceeded the 64 byte limit",
+		},
+		{
+			name:       "limits disabled",
+			maxRequest: 0, maxResponse: 0,
+			request: large, contentType: "application/json", response: json64 + json64, chunked: true,
+			wantStatus: http.StatusOK, wantHits: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var hits atomic.Int32
+			worker := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				hits.Add(1)
+				w.Header().Set("Content-Type", tt.contentType)
+				if tt.chunked {
+					w.(http.Flusher).Flush()
+				}
+				w.Write([]byte(tt.response))
+			}))
+			defer worker.Close()
+
+			s := NewModelService([]ModelWorker{{Name: "a", URL: worker.URL, Model: "m", Streaming: true}},
+				map[string]ModelInfo{"m": {ID: "m"}})
+			s.SetBodyLimits(tt.maxRequest, tt.maxResponse)
+			logger, logs := test.NewNullLogger()
+			s.Logger = logger
+
+			rec, aborted := chatRequestRecover(s, tt.request)
+			if aborted != tt.wantAbort {
+				t.Fatalf("aborted = %v, want %v", aborted, tt.wantAbort)
+			}
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if n := hits.Load(); n != tt.wantHits {
+				t.Errorf("worker hits = %d, want %d", n, tt.wantHits)
+			}
+			if tt.wantLog != "" {
+				if entry := logs.LastEntry(); entry == nil || !strings.Contains(entry.Message, tt.wantLog) {
+					t.Errorf("last log entry = %v, want it to mention %q", entry, tt.wantLog)
+				}
+			}
+
+			switch {
+			case tt.wantCode != "":
+				var envelope apierror.Envelope
+				if err := json.NewDecoder(rec.Body).Decode(&envelope); err != nil || envelope.Error.Code != tt.wantCode {
+					t.Errorf("envelope = %+v (%v), want code %s", envelope.Error, err, tt.wantCode)
+				}
+			case tt.wantAbort:
+				// 已转发的部分不超过上限，超限被记为上游错误
+				if int64(rec.Body.Len()) > tt.maxResponse {
+					t.Errorf("forwarded %d bytes, want at most %d", rec.Body.Len(), tt.maxResponse)
+				}
+				if stats := s.stats.snapshot()["m"]; stats.Errors != 1 {
+					t.Errorf("aborted stream recorded %d errors, want 1", stats.Errors)
+				}
+			default:
+				if rec.Body.String() != tt.response {
+					t.Errorf("body = %q, want the worker response", rec.Body.String())
+				}
+			}
+		})
+	}
+}
+
+// chatRequestRecover 调用聊天接口，并报告处理器是否以http.ErrAbortHandler中断
+func chatRequestRecover(s *ModelService, body string) (rec *httptest.ResponseRecorder, aborted bool) {
+	rec = httptest.NewRecorder()
+	defer func() {
+		if v := recover(); v != nil {
+			if v != http.ErrAbortHandler {
+				panic(v)
+			}
+			aborted = true
+		}
+	}()
+	s.HandleChatRequest(rec, httptest.NewRequest(http.MethodPost, "/mcp/v1/chat", strings.NewReader(body)))
+	return rec, false
+}
+
+func TestReadLimited(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		limit   int64
+		wantErr error
+	}{
+		{"below limit", "abc", 4, nil},
+		{"at limit", "abcd", 4, nil},
+		{"above limit", "abcde", 4, errResponseTooLarge},
+		{"unlimited", strings.Repeat("a", 1000), 0, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := readLimited(strings.NewReader(tt.body), tt.limit)
+			if err != tt.wantErr {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && string(data) != tt.body {
+				t.Errorf("data = %q, want %q", data, tt.body)
+			}
+		})
+	}
+}
+
+func TestCopyLimited(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		limit   int64
+		want    string
+		wantErr error
+	}{
+		{"below limit", "abc", 4, "abc", nil},
+		{"at limit", "abcd", 4, "abcd", nil},
+		{"above limit", "abcde", 4, "abcd", errResponseTooLarge},
+		{"unlimited", "abcdef", 0, "abcdef", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var dst strings.Builder
+			n, err := copyLimited(&dst, strings.NewReader(tt.body), tt.limit)
+			if err != tt.wantErr {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if dst.String() != tt.want || n != int64(len(tt.want)) {
+				t.Errorf("copied %d bytes %q, want %q", n, dst.String(), tt.want)
+			}
+		})
+	}
+}
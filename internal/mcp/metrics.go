@@ -0,0 +1,58 @@
+package mcp
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// metricsRegistry MCP服务独立的指标注册表，避免与默认注册表重复注册
+var metricsRegistry = prometheus.NewRegistry()
+
+var (
+	// workerUp 工作节点存活状态(1为健康，0为不可用)
+	workerUp = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "mcp",
+		Name:      "worker_up",
+		Help:      "Whether the model worker passed its last health probe (1) or not (0).",
+	}, []string{"worker", "model"})
+
+	// healthyWorkers 当前健康的工作节点数量
+	healthyWorkers = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "mcp",
+		Name:      "healthy_workers",
+		Help:      "Number of model workers that passed their last health probe.",
+	})
+
+	// chatRequests 按模型和状态码类别统计的聊天请求数
+	chatRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "mcp",
+		Name:      "chat_requests_total",
+		Help:      "Chat completion requests handled, by model and response status class.",
+	}, []string{"model", "status"})
+
+	// chatDuration 按模型统计的聊天请求耗时
+	chatDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "mcp",
+		Name:      "chat_request_duration_seconds",
+		Help:      "Chat completion request latency in seconds, by model.",
+		Buckets:   []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120},
+	}, []string{"model"})
+
+	// chatTokens 按模型和类型(prompt/completion)统计的token用量
+	chatTokens = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "mcp",
+		Name:      "chat_tokens_total",
+		Help:      "Tokens reported by model workers, by model and type (prompt or completion).",
+	}, []string{"model", "type"})
+)
+
+func init() {
+	metricsRegistry.MustRegister(workerUp, healthyWorkers, chatRequests, chatDuration, chatTokens)
+}
+
+// MetricsHandler 返回暴露MCP服务指标的HTTP处理器
+func MetricsHandler() http.Handler {
+	return promhttp.HandlerFor(metricsRegistry, promhttp.HandlerOpts{})
+}
@@ -2,11 +2,20 @@ package mcp
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"time"
+
+	"ai-gatway/pkg/apierror"
+	"ai-gatway/pkg/httpx"
+	"ai-gatway/pkg/tracing"
+
+	"github.com/sirupsen/logrus"
 )
 
 // ModelWorker 表示一个模型工作节点
@@ -62,78 +71,218 @@ type ModelInfo struct {
 	Description   string   `json:"description"`
 	ContextLength int      `json:"context_length"`
 	Capabilities  []string `json:"capabilities"`
+	SystemPrompt  string   `json:"-"`
 }
 
 // ModelService 处理模型相关请求的服务
 type ModelService struct {
 	Workers []ModelWorker
 	Models  map[string]ModelInfo
+	Health  *HealthChecker
+	Logger  logrus.FieldLogger
+
+	pool             *workerPool
+	stats            *statsWindow
+	clients          map[string]*httpx.Client
+	transports       map[string]http.RoundTripper
+	maxRequestBytes  int64
+	maxResponseBytes int64
 }
 
 // NewModelService 创建模型服务
 func NewModelService(workers []ModelWorker, models map[string]ModelInfo) *ModelService {
-	return &ModelService{
+	s := &ModelService{
 		Workers: workers,
 		Models:  models,
+		Logger:  logrus.StandardLogger(),
+		pool:    newWorkerPool(),
+		stats:   newStatsWindow(),
+
+		maxRequestBytes:  defaultMaxRequestBytes,
+		maxResponseBytes: defaultMaxResponseBytes,
 	}
+	s.SetClientConfig(httpx.Config{}, httpx.TransportConfig{})
+	s.Health = s.NewHealthChecker(defaultHealthTimeout, defaultHealthCacheTTL)
+	return s
 }
 
-// findWorker 根据模型名称查找对应的工作节点
-func (s *ModelService) findWorker(modelName string) *ModelWorker {
+// SetClientConfig 为每个工作节点创建HTTP客户端，工作节点配置的超时优先于cfg.Timeout。
+// cfg.Transport为nil时，指向同一主机的工作节点共享一个连接池，健康检查也复用该连接池。
+func (s *ModelService) SetClientConfig(cfg httpx.Config, transportCfg httpx.TransportConfig) {
+	hostTransports := make(map[string]http.RoundTripper)
+
+	clients := make(map[string]*httpx.Client, len(s.Workers))
+	transports := make(map[string]http.RoundTripper, len(s.Workers))
 	for _, worker := range s.Workers {
-		if worker.Model == modelName {
-			return &worker
+		workerCfg := cfg
+		if worker.Timeout > 0 {
+			workerCfg.Timeout = time.Duration(worker.Timeout) * time.Second
+		}
+		transport := cfg.Transport
+		if transport == nil {
+			host := worker.URL
+			if u, err := url.Parse(worker.URL); err == nil {
+				host = u.Host
+			}
+			if _, ok := hostTransports[host]; !ok {
+				hostTransports[host] = httpx.NewTransport(transportCfg)
+			}
+			transport = hostTransports[host]
+			workerCfg.Transport = tracing.Transport(transport)
 		}
+		clients[worker.Name] = httpx.New(workerCfg)
+		transports[worker.Name] = transport
 	}
-	return nil
+	s.clients = clients
+	s.transports = transports
+}
+
+// forward 将请求转发到指定工作节点
+func (s *ModelService) forward(ctx context.Context, worker ModelWorker, requestBody []byte, requestID string) (*http.Response, error) {
+	// 创建新请求
+	req, err := http.NewRequestWithContext(ctx, "POST", worker.URL+"/v1/chat/completions", bytes.NewBuffer(requestBody))
+	if err != nil {
+		return nil, err
+	}
+
+	// 设置请求头
+	req.Header.Set("Content-Type", "application/json")
+	if requestID != "" {
+		req.Header.Set(apierror.RequestIDHeader, requestID)
+	}
+
+	// 发送请求
+	return s.clients[worker.Name].Do(req)
+}
+
+// hasWorker 判断是否配置了服务该模型的工作节点
+func (s *ModelService) hasWorker(model string) bool {
+	for _, worker := range s.Workers {
+		if worker.Model == model {
+			return true
+		}
+	}
+	return false
 }
 
 // HandleChatRequest 处理聊天请求
 func (s *ModelService) HandleChatRequest(w http.ResponseWriter, r *http.Request) {
-	// 解析请求
 	var request ChatRequest
-	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+	var capture *usageCapture
+
+	// 记录请求指标和用量
+	start := time.Now()
+	recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+	w = recorder
+	defer func() {
+		var usage *ChatUsage
+		if capture != nil {
+			usage = capture.Usage()
+		}
+		s.recordChat(request.Model, recorder.status, time.Since(start), usage)
+	}()
+
+	// 解析请求(限制请求体大小)
+	body := r.Body
+	if s.maxRequestBytes > 0 {
+		body = http.MaxBytesReader(w, r.Body, s.maxRequestBytes)
+	}
+	if err := json.NewDecoder(body).Decode(&request); err != nil {
+		writeDecodeError(w, r, err)
 		return
 	}
 
-	// 查找对应的模型工作节点
-	worker := s.findWorker(request.Model)
-	if worker == nil {
-		http.Error(w, fmt.Sprintf("Model %s not found", request.Model), http.StatusNotFound)
+	// 目录中没有的模型返回404
+	if _, ok := s.Models[request.Model]; request.Model != "" && !ok {
+		apierror.WriteError(w, r, http.StatusNotFound, apierror.CodeNotFound, fmt.Sprintf("Model %s not found", request.Model))
 		return
 	}
 
-	// 准备转发请求
-	requestBody, err := json.Marshal(request)
-	if err != nil {
-		http.Error(w, "Failed to prepare request", http.StatusInternalServerError)
+	// 注入模型默认系统消息并校验请求
+	s.applyModelDefaults(&request)
+	if errs := s.validateChatRequest(&request); len(errs) > 0 {
+		writeValidationErrors(w, r, errs)
 		return
 	}
 
-	// 设置超时
-	client := &http.Client{
-		Timeout: time.Duration(worker.Timeout) * time.Second,
+	// 查找可处理该模型的工作节点(按优先级和轮询排序)
+	candidates := s.pool.candidates(s.Workers, request.Model, request.Stream)
+	if len(candidates) == 0 && request.Stream && s.hasWorker(request.Model) {
+		writeValidationErrors(w, r, ValidationErrors{{
+			Field:   "stream",
+			Message: fmt.Sprintf("model %s does not support streaming", request.Model),
+		}})
+		return
 	}
-
-	// 创建新请求
-	req, err := http.NewRequest("POST", worker.URL+"/v1/chat/completions", bytes.NewBuffer(requestBody))
-	if err != nil {
-		http.Error(w, "Failed to create request", http.StatusInternalServerError)
+	if len(candidates) == 0 {
+		apierror.WriteError(w, r, http.StatusNotFound, apierror.CodeNotFound, fmt.Sprintf("Model %s not found", request.Model))
 		return
 	}
 
-	// 设置请求头
-	req.Header.Set("Content-Type", "application/json")
+	// 依次尝试候选节点，连接失败时切换到下一个
+	var resp *http.Response
+	var err error
+	for _, worker := range candidates {
+		// 按节点上限裁剪max_tokens后准备转发请求
+		upstream := request
+		upstream.MaxTokens = s.clampMaxTokens(request, worker)
+		requestBody, marshalErr := json.Marshal(upstream)
+		if marshalErr != nil {
+			apierror.WriteError(w, r, http.StatusInternalServerError, apierror.CodeInternal, "Failed to prepare request")
+			return
+		}
 
-	// 发送请求
-	resp, err := client.Do(req)
+		resp, err = s.forward(r.Context(), worker, requestBody, r.Header.Get(apierror.RequestIDHeader))
+		if err == nil {
+			s.pool.markSuccess(worker.Name)
+			break
+		}
+		if r.Context().Err() != nil {
+			// 客户端已断开，不计为节点故障
+			break
+		}
+		s.pool.markFailure(worker.Name)
+		s.Logger.WithFields(logrus.Fields{
+			"worker":     worker.Name,
+			"model":      request.Model,
+			"request_id": r.Header.Get(apierror.RequestIDHeader),
+		}).Warnf("Model worker request failed: %v", err)
+	}
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to connect to model worker: %v", err), http.StatusInternalServerError)
+		apierror.WriteError(w, r, http.StatusBadGateway, apierror.CodeUpstreamUnavailable, fmt.Sprintf("Failed to connect to model worker: %v", err))
 		return
 	}
 	defer resp.Body.Close()
 
+	logger := s.Logger.WithFields(logrus.Fields{
+		"model":      request.Model,
+		"request_id": r.Header.Get(apierror.RequestIDHeader),
+	})
+	if s.maxResponseBytes > 0 && resp.ContentLength > s.maxResponseBytes {
+		logger.Warnf("Model worker response of %d bytes exceeds the %d byte limit", resp.ContentLength, s.maxResponseBytes)
+		apierror.WriteError(w, r, http.StatusBadGateway, apierror.CodeUpstreamUnavailable, "Model worker response too large")
+		return
+	}
+
+	// 按上游实际返回的格式转发和解析usage，与请求中的stream参数无关
+	stream := isEventStream(resp)
+	capture = &usageCapture{stream: stream}
+
+	// 非流式响应先完整读取，超过上限时返回502
+	var data []byte
+	if !stream {
+		data, err = readLimited(resp.Body, s.maxResponseBytes)
+		if err != nil {
+			logger.Warnf("Failed to read model worker response: %v", err)
+			message := "Failed to read model worker response"
+			if errors.Is(err, errResponseTooLarge) {
+				message = "Model worker response too large"
+			}
+			apierror.WriteError(w, r, http.StatusBadGateway, apierror.CodeUpstreamUnavailable, message)
+			return
+		}
+	}
+
 	// 转发响应头
 	for name, values := range resp.Header {
 		for _, value := range values {
@@ -144,8 +293,19 @@ func (s *ModelService) HandleChatRequest(w http.ResponseWriter, r *http.Request)
 	// 设置响应状态码
 	w.WriteHeader(resp.StatusCode)
 
-	// 转发响应体
-	io.Copy(w, resp.Body)
+	// 转发响应体，同时解析usage
+	if !stream {
+		w.Write(data)
+		capture.Write(data)
+		return
+	}
+
+	// 流式响应已发出状态码，超过上限时只能中断连接
+	if _, err := copyLimited(w, io.TeeReader(resp.Body, capture), s.maxResponseBytes); errors.Is(err, errResponseTooLarge) {
+		logger.Warnf("Model worker stream exceeded the %d byte limit, aborting", s.maxResponseBytes)
+		recorder.status = http.StatusBadGateway
+		panic(http.ErrAbortHandler)
+	}
 }
 
 // HandleListModels 处理列出模型请求
@@ -196,10 +356,11 @@ func (d *modelServiceDecorator) HandleRequest(w http.ResponseWriter, r *http.Req
 		d.model.HandleChatRequest(w, r)
 	case path == "/mcp/v1/models":
 		d.model.HandleListModels(w, r)
+	case path == "/mcp/v1/stats":
+		d.model.HandleStats(w, r)
 	case path == "/health":
 		// 健康检查
-		w.Header().Set("Content-Type", "application/json")
-		fmt.Fprintf(w, `{"status":"ok"}`)
+		d.model.HandleHealth(w, r)
 	default:
 		// 默认处理
 		d.service.HandleRequest(w, r)
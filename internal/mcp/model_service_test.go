@@ -0,0 +1,72 @@
+package mcp
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"ai-gatway/pkg/apierror"
+)
+
+// chatRequest 以JSON请求体调用聊天接口
+func chatRequest(s *ModelService, body string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	s.HandleChatRequest(rec, httptest.NewRequest(http.MethodPost, "/mcp/v1/chat", strings.NewReader(body)))
+	return rec
+}
+
+func TestChatFailsOverToNextWorker(t *testing.T) {
+	var served atomic.Int32
+	second := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		served.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"ok"}`))
+	}))
+	defer second.Close()
+
+	workers := []ModelWorker{
+		{Name: "refusing", URL: deadWorkerURL(t), Model: "m", Priority: 1},
+		{Name: "serving", URL: second.URL, Model: "m", Priority: 2},
+	}
+	s := NewModelService(workers, map[string]ModelInfo{"m": {ID: "m"}})
+
+	rec := chatRequest(s, `{"model":"m","messages":[{"role":"user","content":"hi"}]}`)
+	if rec.Code != http.StatusOK || rec.Body.String() != `{"id":"ok"}` {
+		t.Fatalf("got %d %s, want the second worker's response", rec.Code, rec.Body.String())
+	}
+	if served.Load() != 1 {
+		t.Errorf("second worker served %d requests, want 1", served.Load())
+	}
+}
+
+func TestChatAllWorkersDown(t *testing.T) {
+	workers := []ModelWorker{
+		{Name: "a", URL: deadWorkerURL(t), Model: "m"},
+		{Name: "b", URL: deadWorkerURL(t), Model: "m"},
+	}
+	s := NewModelService(workers, map[string]ModelInfo{"m": {ID: "m"}})
+
+	rec := chatRequest(s, `{"model":"m","messages":[{"role":"user","content":"hi"}]}`)
+	if rec.Code != http.StatusBadGateway {
+		t.Errorf("status = %d, want 502", rec.Code)
+	}
+}
+
+func TestChatStreamWithoutStreamingWorker(t *testing.T) {
+	s := NewModelService([]ModelWorker{{Name: "a", URL: "http://127.0.0.1:1", Model: "m"}}, map[string]ModelInfo{"m": {ID: "m"}})
+
+	rec := chatRequest(s, `{"model":"m","stream":true,"messages":[{"role":"user","content":"hi"}]}`)
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("status = %d, want 422", rec.Code)
+	}
+	var envelope apierror.Envelope
+	if err := json.Unmarshal(rec.Body.Bytes(), &envelope); err != nil {
+		t.Fatal(err)
+	}
+	if envelope.Error.Code != apierror.CodeValidation || !strings.Contains(rec.Body.String(), "does not support streaming") {
+		t.Errorf("unexpected error body: %s", rec.Body.String())
+	}
+}
@@ -2,8 +2,13 @@ package mcp
 
 import (
 	"fmt"
-	"log"
 	"net/http"
+	"time"
+
+	"ai-gatway/pkg/apierror"
+	"ai-gatway/pkg/utils"
+
+	"github.com/sirupsen/logrus"
 )
 
 // Service 定义MCP服务接口
@@ -27,20 +32,39 @@ func (s *BaseService) HandleRequest(w http.ResponseWriter, r *http.Request) {
 // loggingDecorator 日志装饰器
 type loggingDecorator struct {
 	service Service
+	logger  logrus.FieldLogger
 }
 
 // WithLogging 添加日志功能的装饰器
-func WithLogging(service Service) Service {
-	return &loggingDecorator{service: service}
+func WithLogging(service Service, logger logrus.FieldLogger) Service {
+	return &loggingDecorator{service: service, logger: logger}
 }
 
 func (d *loggingDecorator) HandleRequest(w http.ResponseWriter, r *http.Request) {
+	// 沿用网关传入的请求ID，直接访问时生成新的ID
+	requestID := r.Header.Get(apierror.RequestIDHeader)
+	if requestID == "" {
+		requestID = utils.NewRequestID()
+		r.Header.Set(apierror.RequestIDHeader, requestID)
+	}
+	w.Header().Set(apierror.RequestIDHeader, requestID)
+
 	// 记录请求信息
-	log.Printf("MCP request: %s %s from %s", r.Method, r.URL.Path, r.RemoteAddr)
+	entry := d.logger.WithFields(logrus.Fields{
+		"route":      r.URL.Path,
+		"method":     r.Method,
+		"request_id": requestID,
+	})
+	entry.WithField("remote_addr", r.RemoteAddr).Debug("MCP request")
 
-	// 调用实际处理
-	d.service.HandleRequest(w, r)
+	// 调用实际处理，记录状态码和耗时
+	start := time.Now()
+	recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+	d.service.HandleRequest(recorder, r)
 
 	// 记录响应信息
-	log.Printf("MCP response: %s %s", r.Method, r.URL.Path)
+	entry.WithFields(logrus.Fields{
+		"status":   recorder.status,
+		"duration": time.Since(start).String(),
+	}).Info("MCP response")
 }
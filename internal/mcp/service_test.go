@@ -0,0 +1,120 @@
+package mcp
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"ai-gatway/pkg/apierror"
+	"ai-gatway/pkg/logger"
+
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+func TestLoggingAssignsRequestIDToChatRoutes(t *testing.T) {
+	logger, _ := test.NewNullLogger()
+	// 工作节点不可达，返回502错误信封
+	model := NewModelService([]ModelWorker{{Name: "dead", URL: "http://127.0.0.1:1", Model: "m"}}, map[string]ModelInfo{"m": {ID: "m"}})
+	model.Logger = logger
+	service := WithLogging(WithModelService(NewBaseService(), model), logger)
+
+	req := httptest.NewRequest(http.MethodPost, "/mcp/v1/chat", strings.NewReader(`{"model":"m","messages":[{"role":"user","content":"hi"}]}`))
+	rec := httptest.NewRecorder()
+	service.HandleRequest(rec, req)
+
+	requestID := rec.Header().Get(apierror.RequestIDHeader)
+	if requestID == "" {
+		t.Fatal("response has no X-Request-ID")
+	}
+	if rec.Code != http.StatusBadGateway {
+		t.Fatalf("status = %d, want 502", rec.Code)
+	}
+	var envelope apierror.Envelope
+	if err := json.Unmarshal(rec.Body.Bytes(), &envelope); err != nil {
+		t.Fatalf("invalid error envelope: %v", err)
+	}
+	if envelope.Error.RequestID != requestID {
+		t.Errorf("envelope request_id = %q, want %q", envelope.Error.RequestID, requestID)
+	}
+
+}
+
+func TestLoggingKeepsIncomingRequestID(t *testing.T) {
+	logger, _ := test.NewNullLogger()
+	model := NewModelService(nil, map[string]ModelInfo{})
+	service := WithLogging(WithModelService(NewBaseService(), model), logger)
+
+	req := httptest.NewRequest(http.MethodGet, "/mcp/v1/models", nil)
+	req.Header.Set(apierror.RequestIDHeader, "abc123")
+	rec := httptest.NewRecorder()
+	service.HandleRequest(rec, req)
+
+	if got := rec.Header().Get(apierror.RequestIDHeader); got != "abc123" {
+		t.Errorf("X-Request-ID = %q, want abc123", got)
+	}
+}
+
+func TestLoggingWritesAccessLogForChatRoutes(t *testing.T) {
+	appLog, err := logger.New(logger.Config{Service: "mcp", Level: "info"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	appLog.Logger.SetOutput(io.Discard)
+	hook := test.NewLocal(appLog.Logger)
+
+	model := NewModelService(nil, map[string]ModelInfo{"m": {ID: "m"}})
+	model.Logger = appLog
+	service := WithLogging(WithModelService(NewBaseService(), model), appLog)
+
+	req := httptest.NewRequest(http.MethodPost, "/mcp/v1/chat", strings.NewReader(`{"model":"m","messages":[{"role":"user","content":"hi"}]}`))
+	service.HandleRequest(httptest.NewRecorder(), req)
+
+	entry := hook.LastEntry()
+	if entry == nil || entry.Message != "MCP response" {
+		t.Fatalf("missing access log entry, got %v", entry)
+	}
+	for _, field := range []string{"service", "route", "method", "request_id", "status", "duration"} {
+		if _, ok := entry.Data[field]; !ok {
+			t.Errorf("access log is missing field %q: %v", field, entry.Data)
+		}
+	}
+	if entry.Data["service"] != "mcp" || entry.Data["route"] != "/mcp/v1/chat" || entry.Data["status"] != http.StatusNotFound {
+		t.Errorf("unexpected access log fields: %v", entry.Data)
+	}
+}
+
+func TestLoggingForwardsRequestIDToWorker(t *testing.T) {
+	var received atomic.Value
+	worker := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received.Store(r.Header.Get(apierror.RequestIDHeader))
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"ok"}`))
+	}))
+	defer worker.Close()
+
+	logger, _ := test.NewNullLogger()
+	model := NewModelService([]ModelWorker{{Name: "a", URL: worker.URL, Model: "m"}}, map[string]ModelInfo{"m": {ID: "m"}})
+	service := WithLogging(WithModelService(NewBaseService(), model), logger)
+
+	for _, incoming := range []string{"", "abc123"} {
+		req := httptest.NewRequest(http.MethodPost, "/mcp/v1/chat",
+			strings.NewReader(`{"model":"m","messages":[{"role":"user","content":"hi"}]}`))
+		if incoming != "" {
+			req.Header.Set(apierror.RequestIDHeader, incoming)
+		}
+		rec := httptest.NewRecorder()
+		service.HandleRequest(rec, req)
+
+		requestID := rec.Header().Get(apierror.RequestIDHeader)
+		if rec.Code != http.StatusOK || requestID == "" || (incoming != "" && requestID != incoming) {
+			t.Fatalf("status %d, request ID %q, incoming %q", rec.Code, requestID, incoming)
+		}
+		if got, _ := received.Load().(string); got != requestID {
+			t.Errorf("worker received request ID %q, want %q", got, requestID)
+		}
+	}
+}
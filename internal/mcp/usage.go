@@ -0,0 +1,229 @@
+package mcp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// 用量统计参数
+const (
+	// maxUsageCaptureBytes 非流式响应中用于解析usage的最大缓存字节数
+	maxUsageCaptureBytes = 1 << 20
+	// statsBucketSize 滚动窗口中每个桶的时长
+	statsBucketSize = time.Minute
+	// statsBucketCount 滚动窗口的桶数量
+	statsBucketCount = 15
+)
+
+// ChatUsage 表示一次聊天请求的token用量
+type ChatUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// usageCapture 在转发响应体的同时解析上游返回的usage字段。
+// 非流式响应缓存整个响应体后解析；流式响应逐行解析"data:"事件，保留最后一个带usage的事件。
+type usageCapture struct {
+	stream   bool
+	buf      bytes.Buffer
+	overflow bool
+	usage    *ChatUsage
+}
+
+func (c *usageCapture) Write(p []byte) (int, error) {
+	if !c.stream {
+		if !c.overflow && c.buf.Len()+len(p) <= maxUsageCaptureBytes {
+			c.buf.Write(p)
+		} else {
+			c.overflow = true
+		}
+		return len(p), nil
+	}
+
+	c.buf.Write(p)
+	for {
+		line, err := c.buf.ReadBytes('\n')
+		if err != nil {
+			// 不完整的行留到下一次写入
+			remaining := append([]byte{}, line...)
+			c.buf.Reset()
+			c.buf.Write(remaining)
+			break
+		}
+		c.parseEvent(line)
+	}
+	return len(p), nil
+}
+
+// parseEvent 解析一行SSE事件中的usage
+func (c *usageCapture) parseEvent(line []byte) {
+	line = bytes.TrimSpace(line)
+	if !bytes.HasPrefix(line, []byte("data:")) {
+		return
+	}
+	c.parseUsage(bytes.TrimSpace(bytes.TrimPrefix(line, []byte("data:"))))
+}
+
+// parseUsage 从JSON中提取usage字段
+func (c *usageCapture) parseUsage(data []byte) {
+	var payload struct {
+		Usage *ChatUsage `json:"usage"`
+	}
+	if err := json.Unmarshal(data, &payload); err != nil || payload.Usage == nil {
+		return
+	}
+	if payload.Usage.TotalTokens > 0 || payload.Usage.PromptTokens > 0 || payload.Usage.CompletionTokens > 0 {
+		c.usage = payload.Usage
+	}
+}
+
+// Usage 返回解析到的用量，没有时返回nil
+func (c *usageCapture) Usage() *ChatUsage {
+	if !c.stream && !c.overflow && c.usage == nil {
+		c.parseUsage(c.buf.Bytes())
+	}
+	if c.stream && c.usage == nil && c.buf.Len() > 0 {
+		c.parseEvent(c.buf.Bytes())
+	}
+	return c.usage
+}
+
+// statusRecorder 记录写入的响应状态码
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Flush 透传流式响应的刷新
+func (r *statusRecorder) Flush() {
+	if flusher, ok := r.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// statusClass 将状态码转换为"2xx"形式的类别
+func statusClass(status int) string {
+	return fmt.Sprintf("%dxx", status/100)
+}
+
+// ModelStats 表示某个模型在滚动窗口内的统计
+type ModelStats struct {
+	Requests         int     `json:"requests"`
+	Errors           int     `json:"errors"`
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	AvgLatencyMs     float64 `json:"avg_latency_ms"`
+}
+
+// statsBucket 滚动窗口中的一个时间桶
+type statsBucket struct {
+	start     time.Time
+	stats     ModelStats
+	latencyMs float64
+}
+
+// statsWindow 按模型记录最近一段时间的请求统计
+type statsWindow struct {
+	mu      sync.Mutex
+	buckets map[string]*[statsBucketCount]statsBucket
+}
+
+// newStatsWindow 创建滚动统计窗口
+func newStatsWindow() *statsWindow {
+	return &statsWindow{
+		buckets: make(map[string]*[statsBucketCount]statsBucket),
+	}
+}
+
+// record 记录一次请求
+func (sw *statsWindow) record(model string, status int, latency time.Duration, usage *ChatUsage) {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+
+	now := time.Now().Truncate(statsBucketSize)
+	ring, ok := sw.buckets[model]
+	if !ok {
+		ring = &[statsBucketCount]statsBucket{}
+		sw.buckets[model] = ring
+	}
+
+	bucket := &ring[(now.Unix()/int64(statsBucketSize/time.Second))%statsBucketCount]
+	if !bucket.start.Equal(now) {
+		*bucket = statsBucket{start: now}
+	}
+
+	bucket.stats.Requests++
+	if status >= http.StatusBadRequest {
+		bucket.stats.Errors++
+	}
+	bucket.latencyMs += float64(latency) / float64(time.Millisecond)
+	if usage != nil {
+		bucket.stats.PromptTokens += usage.PromptTokens
+		bucket.stats.CompletionTokens += usage.CompletionTokens
+	}
+}
+
+// snapshot 汇总窗口内各模型的统计
+func (sw *statsWindow) snapshot() map[string]ModelStats {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+
+	cutoff := time.Now().Add(-statsBucketSize * statsBucketCount)
+	result := make(map[string]ModelStats)
+	for model, ring := range sw.buckets {
+		var total ModelStats
+		var latencyMs float64
+		for _, bucket := range ring {
+			if bucket.start.IsZero() || !bucket.start.After(cutoff) {
+				continue
+			}
+			total.Requests += bucket.stats.Requests
+			total.Errors += bucket.stats.Errors
+			total.PromptTokens += bucket.stats.PromptTokens
+			total.CompletionTokens += bucket.stats.CompletionTokens
+			latencyMs += bucket.latencyMs
+		}
+		if total.Requests == 0 {
+			continue
+		}
+		total.AvgLatencyMs = latencyMs / float64(total.Requests)
+		result[model] = total
+	}
+	return result
+}
+
+// recordChat 记录一次聊天请求的指标和滚动统计
+func (s *ModelService) recordChat(model string, status int, latency time.Duration, usage *ChatUsage) {
+	// 只使用目录中的模型作为标签，避免任意输入导致标签基数膨胀
+	if _, ok := s.Models[model]; !ok {
+		model = "unknown"
+	}
+
+	chatRequests.WithLabelValues(model, statusClass(status)).Inc()
+	chatDuration.WithLabelValues(model).Observe(latency.Seconds())
+	if usage != nil {
+		chatTokens.WithLabelValues(model, "prompt").Add(float64(usage.PromptTokens))
+		chatTokens.WithLabelValues(model, "completion").Add(float64(usage.CompletionTokens))
+	}
+
+	s.stats.record(model, status, latency, usage)
+}
+
+// HandleStats 返回滚动窗口内各模型的请求统计
+func (s *ModelService) HandleStats(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"window_seconds": int((statsBucketSize * statsBucketCount).Seconds()),
+		"models":         s.stats.snapshot(),
+	})
+}
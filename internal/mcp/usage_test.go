@@ -0,0 +1,171 @@
+package mcp
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestUsageCapture(t *testing.T) {
+	tests := []struct {
+		name   string
+		stream bool
+		chunks []string
+		want   *ChatUsage
+	}{
+		{
+			name:   "json response",
+			chunks: []string{`{"id":"1","usage":{"prompt_tokens":3,`, `"completion_tokens":5,"total_tokens":8}}`},
+			want:   &ChatUsage{PromptTokens: 3, CompletionTokens: 5, TotalTokens: 8},
+		},
+		{
+			name:   "json without usage",
+			chunks: []string{`{"id":"1"}`},
+		},
+		{
+			name:   "sse with usage in final event",
+			stream: true,
+			chunks: []string{
+				"data: {\"choices\":[{\"delta\":{\"content\":\"he\"}}]}\n\n",
+				"data: {\"choices\":[],\"usa",
+				"ge\":{\"prompt_tokens\":4,\"completion_tokens\":6,\"total_tokens\":10}}\n\n",
+				"data: [DONE]\n\n",
+			},
+			want: &ChatUsage{PromptTokens: 4, CompletionTokens: 6, TotalTokens: 10},
+		},
+		{
+			name:   "sse final event without trailing newline",
+			stream: true,
+			chunks: []string{"data: {\"usage\":{\"prompt_tokens\":1,\"completion_tokens\":1,\"total_tokens\":2}}"},
+			want:   &ChatUsage{PromptTokens: 1, CompletionTokens: 1, TotalTokens: 2},
+		},
+		{
+			name:   "sse without usage",
+			stream: true,
+			chunks: []string{"data: {\"choices\":[]}\n\n", "data: [DONE]\n\n"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			capture := &usageCapture{stream: tt.stream}
+			for _, chunk := range tt.chunks {
+				capture.Write([]byte(chunk))
+			}
+			got := capture.Usage()
+			switch {
+			case tt.want == nil && got != nil:
+				t.Errorf("usage = %+v, want none", *got)
+			case tt.want != nil && (got == nil || *got != *tt.want):
+				t.Errorf("usage = %v, want %+v", got, *tt.want)
+			}
+		})
+	}
+}
+
+// scrapeMetrics 抓取指标并按序列名(含标签)返回样本值
+func scrapeMetrics(t *testing.T) map[string]float64 {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	MetricsHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	scrape, _ := io.ReadAll(rec.Body)
+
+	samples := make(map[string]float64)
+	for _, line := range strings.Split(string(scrape), "\n") {
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		i := strings.LastIndex(line, " ")
+		value, err := strconv.ParseFloat(line[i+1:], 64)
+		if err != nil {
+			t.Fatalf("malformed sample %q: %v", line, err)
+		}
+		samples[line[:i]] = value
+	}
+	return samples
+}
+
+func TestChatMetricsAfterRequests(t *testing.T) {
+	worker := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"usage":{"prompt_tokens":7,"completion_tokens":11,"total_tokens":18}}`))
+	}))
+	defer worker.Close()
+
+	s := NewModelService([]ModelWorker{{Name: "a", URL: worker.URL, Model: "metrics-model"}},
+		map[string]ModelInfo{"metrics-model": {ID: "metrics-model"}})
+
+	// 指标注册表是全局的，只比较请求前后的差值
+	before := scrapeMetrics(t)
+	body := `{"model":"metrics-model","messages":[{"role":"user","content":"hi"}]}`
+	for i := 0; i < 2; i++ {
+		if rec := chatRequest(s, body); rec.Code != http.StatusOK {
+			t.Fatalf("status = %d", rec.Code)
+		}
+	}
+	chatRequest(s, `{"model":"metrics-model","messages":[]}`)
+	after := scrapeMetrics(t)
+
+	for series, want := range map[string]float64{
+		`mcp_chat_requests_total{model="metrics-model",status="2xx"}`:    2,
+		`mcp_chat_requests_total{model="metrics-model",status="4xx"}`:    1,
+		`mcp_chat_tokens_total{model="metrics-model",type="prompt"}`:     14,
+		`mcp_chat_tokens_total{model="metrics-model",type="completion"}`: 22,
+		`mcp_chat_request_duration_seconds_count{model="metrics-model"}`: 3,
+	} {
+		if got := after[series] - before[series]; got != want {
+			t.Errorf("%s increased by %v, want %v", series, got, want)
+		}
+	}
+
+	stats := s.stats.snapshot()["metrics-model"]
+	if stats.Requests != 3 || stats.Errors != 1 || stats.PromptTokens != 14 || stats.CompletionTokens != 22 {
+		t.Errorf("unexpected rolling stats: %+v", stats)
+	}
+}
+
+func TestUsageFollowsUpstreamFormat(t *testing.T) {
+	// 请求的stream参数与上游实际返回的格式不一致时，按上游格式解析usage
+	tests := []struct {
+		name        string
+		stream      bool
+		contentType string
+		body        string
+	}{
+		{
+			name:        "stream request answered with json",
+			stream:      true,
+			contentType: "application/json",
+			body:        `{"usage":{"prompt_tokens":2,"completion_tokens":3,"total_tokens":5}}`,
+		},
+		{
+			name:        "plain request answered with sse",
+			contentType: "text/event-stream",
+			body:        "data: {\"usage\":{\"prompt_tokens\":2,\"completion_tokens\":3,\"total_tokens\":5}}\n\ndata: [DONE]\n\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			worker := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", tt.contentType)
+				w.Write([]byte(tt.body))
+			}))
+			defer worker.Close()
+
+			s := NewModelService([]ModelWorker{{Name: "a", URL: worker.URL, Model: "m", Streaming: true}},
+				map[string]ModelInfo{"m": {ID: "m"}})
+			body := fmt.Sprintf(`{"model":"m","stream":%t,"messages":[{"role":"user","content":"hi"}]}`, tt.stream)
+			if rec := chatRequest(s, body); rec.Code != http.StatusOK || rec.Body.String() != tt.body {
+				t.Fatalf("got %d %q, want the worker response forwarded", rec.Code, rec.Body.String())
+			}
+
+			stats := s.stats.snapshot()["m"]
+			if stats.PromptTokens != 2 || stats.CompletionTokens != 3 {
+				t.Errorf("usage = %d/%d tokens, want 2/3", stats.PromptTokens, stats.CompletionTokens)
+			}
+		})
+	}
+}
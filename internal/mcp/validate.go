@@ -0,0 +1,135 @@
+package mcp
+
+import (
+	"fmt"
+	"net/http"
+	"unicode/utf8"
+
+	"ai-gatway/pkg/apierror"
+)
+
+// validRoles 允许的消息角色
+var validRoles = map[string]bool{
+	"system":    true,
+	"user":      true,
+	"assistant": true,
+}
+
+// FieldError 表示单个字段的校验错误
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationErrors 表示请求校验失败的全部字段错误
+type ValidationErrors []FieldError
+
+func (e ValidationErrors) Error() string {
+	if len(e) == 0 {
+		return "validation failed"
+	}
+	return fmt.Sprintf("validation failed: %s: %s", e[0].Field, e[0].Message)
+}
+
+// validateChatRequest 校验聊天请求的字段，返回全部字段错误
+func (s *ModelService) validateChatRequest(request *ChatRequest) ValidationErrors {
+	var errs ValidationErrors
+
+	// 未知模型不属于校验错误，由HandleChatRequest返回404
+	if request.Model == "" {
+		errs = append(errs, FieldError{Field: "model", Message: "is required"})
+	}
+
+	if len(request.Messages) == 0 {
+		errs = append(errs, FieldError{Field: "messages", Message: "must not be empty"})
+	}
+	for i, msg := range request.Messages {
+		if !validRoles[msg.Role] {
+			errs = append(errs, FieldError{
+				Field:   fmt.Sprintf("messages[%d].role", i),
+				Message: fmt.Sprintf("invalid role %q, expected system, user or assistant", msg.Role),
+			})
+		}
+	}
+
+	if request.Temperature < 0 || request.Temperature > 2 {
+		errs = append(errs, FieldError{Field: "temperature", Message: "must be between 0 and 2"})
+	}
+	if request.TopP < 0 || request.TopP > 1 {
+		errs = append(errs, FieldError{Field: "top_p", Message: "must be between 0 and 1"})
+	}
+	if request.MaxTokens < 0 {
+		errs = append(errs, FieldError{Field: "max_tokens", Message: "must not be negative"})
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	// 校验提示词长度不超过模型上下文
+	model := s.Models[request.Model]
+	if model.ContextLength > 0 && estimatePromptTokens(request.Messages) >= model.ContextLength {
+		errs = append(errs, FieldError{
+			Field:   "messages",
+			Message: fmt.Sprintf("prompt exceeds the %d token context length of model %s", model.ContextLength, request.Model),
+		})
+	}
+
+	return errs
+}
+
+// applyModelDefaults 为请求注入模型配置的默认系统消息
+func (s *ModelService) applyModelDefaults(request *ChatRequest) {
+	model, ok := s.Models[request.Model]
+	if !ok || model.SystemPrompt == "" {
+		return
+	}
+	for _, msg := range request.Messages {
+		if msg.Role == "system" {
+			return
+		}
+	}
+	request.Messages = append([]ChatMessage{{Role: "system", Content: model.SystemPrompt}}, request.Messages...)
+}
+
+// clampMaxTokens 将max_tokens限制在工作节点上限和模型剩余上下文之内
+func (s *ModelService) clampMaxTokens(request ChatRequest, worker ModelWorker) int {
+	limit := worker.MaxTokens
+	if model, ok := s.Models[request.Model]; ok && model.ContextLength > 0 {
+		remaining := model.ContextLength - estimatePromptTokens(request.Messages)
+		if limit <= 0 || remaining < limit {
+			limit = remaining
+		}
+	}
+
+	if limit <= 0 {
+		return request.MaxTokens
+	}
+	if request.MaxTokens == 0 || request.MaxTokens > limit {
+		return limit
+	}
+	return request.MaxTokens
+}
+
+// estimatePromptTokens 粗略估算消息的token数量：
+// ASCII字符按4个字符1个token计算，其他字符(如中文)按1个字符1个token计算，每条消息额外计4个token
+func estimatePromptTokens(messages []ChatMessage) int {
+	tokens := 0
+	for _, msg := range messages {
+		ascii, other := 0, 0
+		for _, r := range msg.Content {
+			if r < utf8.RuneSelf {
+				ascii++
+			} else {
+				other++
+			}
+		}
+		tokens += 4 + other + (ascii+3)/4
+	}
+	return tokens
+}
+
+// writeValidationErrors 以统一错误格式返回校验错误
+func writeValidationErrors(w http.ResponseWriter, r *http.Request, errs ValidationErrors) {
+	apierror.WriteErrorDetails(w, r, http.StatusUnprocessableEntity, apierror.CodeValidation, "Invalid request", errs)
+}
@@ -0,0 +1,162 @@
+package mcp
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"ai-gatway/pkg/apierror"
+)
+
+func TestValidateChatRequest(t *testing.T) {
+	s := NewModelService(nil, map[string]ModelInfo{
+		"m":     {ID: "m"},
+		"small": {ID: "small", ContextLength: 20},
+	})
+	user := []ChatMessage{{Role: "user", Content: "hi"}}
+
+	tests := []struct {
+		name       string
+		request    ChatRequest
+		wantFields []string
+	}{
+		{"valid", ChatRequest{Model: "m", Messages: user, Temperature: 0.7, TopP: 1}, nil},
+		{"missing model", ChatRequest{Messages: user}, []string{"model"}},
+		{"empty messages", ChatRequest{Model: "m"}, []string{"messages"}},
+		{"bad role", ChatRequest{Model: "m", Messages: []ChatMessage{{Role: "user"}, {Role: "tool"}}}, []string{"messages[1].role"}},
+		{"temperature too high", ChatRequest{Model: "m", Messages: user, Temperature: 2.5}, []string{"temperature"}},
+		{"negative temperature", ChatRequest{Model: "m", Messages: user, Temperature: -0.1}, []string{"temperature"}},
+		{"top_p out of range", ChatRequest{Model: "m", Messages: user, TopP: 1.5}, []string{"top_p"}},
+		{"negative max_tokens", ChatRequest{Model: "m", Messages: user, MaxTokens: -1}, []string{"max_tokens"}},
+		{
+			"multiple errors",
+			ChatRequest{Messages: []ChatMessage{{Role: "bot"}}, TopP: -1},
+			[]string{"model", "messages[0].role", "top_p"},
+		},
+		{
+			"prompt exceeds context",
+			ChatRequest{Model: "small", Messages: []ChatMessage{{Role: "user", Content: strings.Repeat("word ", 40)}}},
+			[]string{"messages"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := s.validateChatRequest(&tt.request)
+			var fields []string
+			for _, e := range errs {
+				fields = append(fields, e.Field)
+			}
+			if strings.Join(fields, ",") != strings.Join(tt.wantFields, ",") {
+				t.Errorf("fields = %v, want %v", fields, tt.wantFields)
+			}
+		})
+	}
+}
+
+func TestInvalidChatRequestReturns422(t *testing.T) {
+	s := NewModelService(nil, map[string]ModelInfo{"m": {ID: "m"}})
+
+	rec := chatRequest(s, `{"model":"m","messages":[],"temperature":3}`)
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("status = %d, want 422", rec.Code)
+	}
+	var envelope struct {
+		Error struct {
+			Code    apierror.Code `json:"code"`
+			Details []FieldError  `json:"details"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &envelope); err != nil {
+		t.Fatal(err)
+	}
+	if envelope.Error.Code != apierror.CodeValidation || len(envelope.Error.Details) != 2 {
+		t.Errorf("unexpected envelope: %s", rec.Body.String())
+	}
+}
+
+func TestUnknownModelReturns404(t *testing.T) {
+	s := NewModelService(nil, map[string]ModelInfo{"m": {ID: "m"}})
+
+	// 未知模型优先于字段校验错误
+	for _, body := range []string{
+		`{"model":"other","messages":[{"role":"user","content":"hi"}]}`,
+		`{"model":"other","messages":[]}`,
+	} {
+		rec := chatRequest(s, body)
+		var envelope apierror.Envelope
+		if err := json.Unmarshal(rec.Body.Bytes(), &envelope); err != nil {
+			t.Fatal(err)
+		}
+		if rec.Code != http.StatusNotFound || envelope.Error.Code != apierror.CodeNotFound {
+			t.Errorf("%s: got %d %s, want 404 %s", body, rec.Code, envelope.Error.Code, apierror.CodeNotFound)
+		}
+	}
+}
+
+func TestClampMaxTokens(t *testing.T) {
+	s := NewModelService(nil, map[string]ModelInfo{
+		"m":   {ID: "m"},
+		"ctx": {ID: "ctx", ContextLength: 100},
+	})
+	messages := []ChatMessage{{Role: "user", Content: "hi"}} // 约5个token
+
+	tests := []struct {
+		name      string
+		model     string
+		requested int
+		workerMax int
+		want      int
+	}{
+		{"no limits keeps request", "m", 50, 0, 50},
+		{"no limits and unset", "m", 0, 0, 0},
+		{"worker limit applies when unset", "m", 0, 64, 64},
+		{"worker limit caps request", "m", 500, 64, 64},
+		{"request below worker limit", "m", 32, 64, 32},
+		{"context remainder caps request", "ctx", 500, 0, 100 - estimatePromptTokens(messages)},
+		{"smaller of worker and context", "ctx", 500, 50, 50},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := ChatRequest{Model: tt.model, Messages: messages, MaxTokens: tt.requested}
+			if got := s.clampMaxTokens(request, ModelWorker{MaxTokens: tt.workerMax}); got != tt.want {
+				t.Errorf("clampMaxTokens = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClampedMaxTokensSentUpstream(t *testing.T) {
+	var upstream ChatRequest
+	worker := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&upstream)
+		w.Write([]byte(`{}`))
+	}))
+	defer worker.Close()
+
+	s := NewModelService([]ModelWorker{{Name: "a", URL: worker.URL, Model: "m", MaxTokens: 256}}, map[string]ModelInfo{"m": {ID: "m"}})
+	rec := chatRequest(s, `{"model":"m","max_tokens":4096,"messages":[{"role":"user","content":"hi"}]}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d", rec.Code)
+	}
+	if upstream.MaxTokens != 256 {
+		t.Errorf("upstream max_tokens = %d, want 256", upstream.MaxTokens)
+	}
+}
+
+func TestApplyModelDefaults(t *testing.T) {
+	s := NewModelService(nil, map[string]ModelInfo{"m": {ID: "m", SystemPrompt: "be brief"}})
+
+	request := ChatRequest{Model: "m", Messages: []ChatMessage{{Role: "user", Content: "hi"}}}
+	s.applyModelDefaults(&request)
+	if len(request.Messages) != 2 || request.Messages[0].Role != "system" || request.Messages[0].Content != "be brief" {
+		t.Errorf("system prompt not injected: %+v", request.Messages)
+	}
+
+	request = ChatRequest{Model: "m", Messages: []ChatMessage{{Role: "system", Content: "mine"}, {Role: "user", Content: "hi"}}}
+	s.applyModelDefaults(&request)
+	if len(request.Messages) != 2 || request.Messages[0].Content != "mine" {
+		t.Errorf("existing system message was replaced: %+v", request.Messages)
+	}
+}
@@ -0,0 +1,118 @@
+package mcp
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// 工作节点故障隔离参数
+const (
+	// maxConsecutiveFailures 连续失败达到该次数后暂时跳过该节点
+	maxConsecutiveFailures = 3
+	// failureCooldown 节点被跳过的时长，过后重新参与选择
+	failureCooldown = 30 * time.Second
+)
+
+// workerState 记录工作节点最近的调用结果
+type workerState struct {
+	failures    int
+	lastFailure time.Time
+}
+
+// workerPool 在服务同一模型的多个工作节点间做优先级选择、轮询和故障转移
+type workerPool struct {
+	mu      sync.Mutex
+	states  map[string]*workerState
+	counter map[string]int
+}
+
+// newWorkerPool 创建工作节点池
+func newWorkerPool() *workerPool {
+	return &workerPool{
+		states:  make(map[string]*workerState),
+		counter: make(map[string]int),
+	}
+}
+
+// candidates 返回可处理该模型请求的工作节点，按尝试顺序排列。
+// Priority数值越小优先级越高；同一优先级内轮询；近期连续失败的节点排在最后。
+func (p *workerPool) candidates(workers []ModelWorker, modelName string, stream bool) []ModelWorker {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var healthy, unhealthy []ModelWorker
+	for _, worker := range workers {
+		if worker.Model != modelName {
+			continue
+		}
+		// 流式请求只能发往支持流式输出的节点
+		if stream && !worker.Streaming {
+			continue
+		}
+		if p.isSuspended(worker.Name) {
+			unhealthy = append(unhealthy, worker)
+		} else {
+			healthy = append(healthy, worker)
+		}
+	}
+
+	sort.SliceStable(healthy, func(i, j int) bool {
+		return healthy[i].Priority < healthy[j].Priority
+	})
+
+	// 同一优先级内按轮询计数旋转
+	offset := p.counter[modelName]
+	p.counter[modelName]++
+	for start := 0; start < len(healthy); {
+		end := start
+		for end < len(healthy) && healthy[end].Priority == healthy[start].Priority {
+			end++
+		}
+		rotate(healthy[start:end], offset)
+		start = end
+	}
+
+	// 所有节点都被隔离时仍然尝试，避免请求直接失败
+	return append(healthy, unhealthy...)
+}
+
+// isSuspended 判断节点是否处于故障隔离期，调用方需持有锁
+func (p *workerPool) isSuspended(name string) bool {
+	state, ok := p.states[name]
+	if !ok || state.failures < maxConsecutiveFailures {
+		return false
+	}
+	return time.Since(state.lastFailure) < failureCooldown
+}
+
+// markSuccess 记录节点调用成功，清除失败计数
+func (p *workerPool) markSuccess(name string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.states, name)
+}
+
+// markFailure 记录节点调用失败
+func (p *workerPool) markFailure(name string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	state, ok := p.states[name]
+	if !ok {
+		state = &workerState{}
+		p.states[name] = state
+	}
+	state.failures++
+	state.lastFailure = time.Now()
+}
+
+// rotate 将切片向左旋转n位
+func rotate(workers []ModelWorker, n int) {
+	if len(workers) < 2 {
+		return
+	}
+	n %= len(workers)
+	rotated := append(append([]ModelWorker{}, workers[n:]...), workers[:n]...)
+	copy(workers, rotated)
+}
@@ -0,0 +1,89 @@
+package apierror
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Code 机器可读的错误码
+type Code string
+
+// 通用错误码
+const (
+	CodeBadRequest          Code = "bad_request"
+	CodeValidation          Code = "validation_failed"
+	CodeUnauthorized        Code = "unauthorized"
+	CodeNotFound            Code = "not_found"
+	CodeMethodNotAllowed    Code = "method_not_allowed"
+	CodeConflict            Code = "conflict"
+	CodePayloadTooLarge     Code = "payload_too_large"
+	CodeRateLimited         Code = "rate_limited"
+	CodeUpstreamUnavailable Code = "upstream_unavailable"
+	CodeInternal            Code = "internal_error"
+)
+
+// RequestIDHeader 请求ID的HTTP头
+const RequestIDHeader = "X-Request-ID"
+
+// Error 错误详情
+type Error struct {
+	Code      Code        `json:"code"`
+	Message   string      `json:"message"`
+	Details   interface{} `json:"details,omitempty"`
+	RequestID string      `json:"request_id,omitempty"`
+}
+
+// Envelope 所有HTTP服务统一的错误响应格式
+type Envelope struct {
+	Error Error `json:"error"`
+}
+
+// WriteError 以统一格式写入错误响应
+func WriteError(w http.ResponseWriter, r *http.Request, status int, code Code, message string) {
+	WriteErrorDetails(w, r, status, code, message, nil)
+}
+
+// WriteErrorDetails 以统一格式写入带详情的错误响应
+func WriteErrorDetails(w http.ResponseWriter, r *http.Request, status int, code Code, message string, details interface{}) {
+	envelope := Envelope{
+		Error: Error{
+			Code:    code,
+			Message: message,
+			Details: details,
+		},
+	}
+	if r != nil {
+		envelope.Error.RequestID = r.Header.Get(RequestIDHeader)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(envelope)
+}
+
+// CodeForStatus 根据HTTP状态码返回默认错误码
+func CodeForStatus(status int) Code {
+	switch status {
+	case http.StatusBadRequest:
+		return CodeBadRequest
+	case http.StatusUnprocessableEntity:
+		return CodeValidation
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return CodeUnauthorized
+	case http.StatusNotFound:
+		return CodeNotFound
+	case http.StatusMethodNotAllowed:
+		return CodeMethodNotAllowed
+	case http.StatusConflict:
+		return CodeConflict
+	case http.StatusRequestEntityTooLarge:
+		return CodePayloadTooLarge
+	case http.StatusTooManyRequests:
+		return CodeRateLimited
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return CodeUpstreamUnavailable
+	default:
+		return CodeInternal
+	}
+}
@@ -0,0 +1,62 @@
+package apierror
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWriteErrorDetails(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(RequestIDHeader, "abc")
+	rec := httptest.NewRecorder()
+
+	WriteErrorDetails(rec, req, http.StatusUnprocessableEntity, CodeValidation, "Invalid request", []string{"field"})
+
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("status = %d", rec.Code)
+	}
+	if rec.Header().Get("Content-Type") != "application/json" || rec.Header().Get("X-Content-Type-Options") != "nosniff" {
+		t.Errorf("unexpected headers: %v", rec.Header())
+	}
+	want := `{"error":{"code":"validation_failed","message":"Invalid request","details":["field"],"request_id":"abc"}}` + "\n"
+	if rec.Body.String() != want {
+		t.Errorf("body = %s, want %s", rec.Body.String(), want)
+	}
+}
+
+func TestWriteErrorOmitsEmptyFields(t *testing.T) {
+	rec := httptest.NewRecorder()
+	WriteError(rec, nil, http.StatusNotFound, CodeNotFound, "missing")
+
+	var raw map[string]map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &raw); err != nil {
+		t.Fatal(err)
+	}
+	for _, field := range []string{"details", "request_id"} {
+		if _, ok := raw["error"][field]; ok {
+			t.Errorf("empty %s should be omitted: %s", field, rec.Body.String())
+		}
+	}
+}
+
+func TestCodeForStatus(t *testing.T) {
+	tests := map[int]Code{
+		http.StatusBadRequest:            CodeBadRequest,
+		http.StatusUnprocessableEntity:   CodeValidation,
+		http.StatusForbidden:             CodeUnauthorized,
+		http.StatusNotFound:              CodeNotFound,
+		http.StatusMethodNotAllowed:      CodeMethodNotAllowed,
+		http.StatusConflict:              CodeConflict,
+		http.StatusRequestEntityTooLarge: CodePayloadTooLarge,
+		http.StatusTooManyRequests:       CodeRateLimited,
+		http.StatusGatewayTimeout:        CodeUpstreamUnavailable,
+		http.StatusTeapot:                CodeInternal,
+	}
+	for status, want := range tests {
+		if got := CodeForStatus(status); got != want {
+			t.Errorf("CodeForStatus(%d) = %s, want %s", status, got, want)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package debug
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"net"
+	"net/http"
+	"os"
+	"runtime"
+	"runtime/pprof"
+	"runtime/trace"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"ai-gatway/pkg/apierror"
+
+	"github.com/sirupsen/logrus"
+)
+
+// 性能分析参数
+const (
+	// defaultProfileSeconds CPU profile和trace的默认采集时长
+	defaultProfileSeconds = 30
+	// maxProfileSeconds 单次采集的最长时长
+	maxProfileSeconds = 300
+)
+
+// Config 调试服务配置
+type Config struct {
+	Enabled bool
+	// Addr 监听地址，建议只绑定回环地址，例如"127.0.0.1:6060"
+	Addr string
+}
+
+// Handler 返回挂载在/debug/pprof下的性能分析处理器。
+// 不引入net/http/pprof，因为它会在init时向http.DefaultServeMux注册路由，
+// 而各服务的对外端口正是使用DefaultServeMux。
+func Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", handleIndex)
+	mux.HandleFunc("/debug/pprof/profile", handleCPUProfile)
+	mux.HandleFunc("/debug/pprof/trace", handleTrace)
+	mux.HandleFunc("/debug/pprof/cmdline", handleCmdline)
+	mux.HandleFunc("/debug/pprof/goroutines", handleGoroutineDump)
+	return mux
+}
+
+// Start 在独立端口启动调试服务，未启用时返回nil
+func Start(cfg Config, logger logrus.FieldLogger) (*http.Server, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	if cfg.Addr == "" {
+		return nil, fmt.Errorf("debug server address is required")
+	}
+
+	listener, err := net.Listen("tcp", cfg.Addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", cfg.Addr, err)
+	}
+	if host, _, err := net.SplitHostPort(cfg.Addr); err == nil {
+		if ip := net.ParseIP(host); ip == nil || !ip.IsLoopback() {
+			logger.Warnf("Debug server is listening on non-loopback address %s, profiles are reachable from the network", cfg.Addr)
+		}
+	}
+
+	server := &http.Server{
+		Handler:     Handler(),
+		ReadTimeout: 10 * time.Second,
+		// CPU profile和trace需要在采集时长结束后才写出响应
+		WriteTimeout: (maxProfileSeconds + 30) * time.Second,
+	}
+	go func() {
+		logger.Infof("Debug server listening on %s", listener.Addr())
+		if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			logger.Errorf("Debug server stopped: %v", err)
+		}
+	}()
+	return server, nil
+}
+
+// handleIndex 列出可用的profile，或输出/debug/pprof/{name}指定的profile
+func handleIndex(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Path[len("/debug/pprof/"):]
+	if name != "" {
+		handleProfile(w, r, name)
+		return
+	}
+
+	profiles := pprof.Profiles()
+	sort.Slice(profiles, func(i, j int) bool { return profiles[i].Name() < profiles[j].Name() })
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprintln(w, "<html><head><title>/debug/pprof/</title></head><body><h1>/debug/pprof/</h1><table>")
+	for _, p := range profiles {
+		name := html.EscapeString(p.Name())
+		fmt.Fprintf(w, "<tr><td>%d</td><td><a href=\"%s?debug=1\">%s</a></td></tr>\n", p.Count(), name, name)
+	}
+	fmt.Fprintln(w, "</table><ul>")
+	fmt.Fprintln(w, "<li><a href=\"profile?seconds=30\">profile</a> (CPU)</li>")
+	fmt.Fprintln(w, "<li><a href=\"trace?seconds=5\">trace</a></li>")
+	fmt.Fprintln(w, "<li><a href=\"goroutines\">goroutines</a> (full stack dump)</li>")
+	fmt.Fprintln(w, "</ul></body></html>")
+}
+
+// handleProfile 输出指定名称的profile，debug=0时为pprof二进制格式，否则为文本
+func handleProfile(w http.ResponseWriter, r *http.Request, name string) {
+	profile := pprof.Lookup(name)
+	if profile == nil {
+		apierror.WriteError(w, r, http.StatusNotFound, apierror.CodeNotFound, fmt.Sprintf("unknown profile %q", name))
+		return
+	}
+
+	debug, _ := strconv.Atoi(r.URL.Query().Get("debug"))
+	if name == "heap" && r.URL.Query().Get("gc") != "" {
+		runtime.GC()
+	}
+	if debug != 0 {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	} else {
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+	}
+	profile.WriteTo(w, debug)
+}
+
+// handleCPUProfile 采集指定时长(seconds参数)的CPU profile
+func handleCPUProfile(w http.ResponseWriter, r *http.Request) {
+	seconds, err := profileSeconds(r)
+	if err != nil {
+		apierror.WriteError(w, r, http.StatusBadRequest, apierror.CodeBadRequest, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", `attachment; filename="profile"`)
+	if err := pprof.StartCPUProfile(w); err != nil {
+		// 同一时间只能有一个CPU profile
+		w.Header().Del("Content-Disposition")
+		apierror.WriteError(w, r, http.StatusConflict, apierror.CodeConflict, fmt.Sprintf("could not enable CPU profiling: %v", err))
+		return
+	}
+	sleep(r.Context(), seconds)
+	pprof.StopCPUProfile()
+}
+
+// handleTrace 采集指定时长(seconds参数)的执行追踪
+func handleTrace(w http.ResponseWriter, r *http.Request) {
+	seconds, err := profileSeconds(r)
+	if err != nil {
+		apierror.WriteError(w, r, http.StatusBadRequest, apierror.CodeBadRequest, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", `attachment; filename="trace"`)
+	if err := trace.Start(w); err != nil {
+		w.Header().Del("Content-Disposition")
+		apierror.WriteError(w, r, http.StatusConflict, apierror.CodeConflict, fmt.Sprintf("could not enable tracing: %v", err))
+		return
+	}
+	sleep(r.Context(), seconds)
+	trace.Stop()
+}
+
+// handleCmdline 输出进程启动参数
+func handleCmdline(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprint(w, strings.Join(os.Args, "\x00"))
+}
+
+// handleGoroutineDump 以panic时的格式输出所有goroutine的完整调用栈
+func handleGoroutineDump(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	pprof.Lookup("goroutine").WriteTo(w, 2)
+}
+
+// profileSeconds 解析采集时长参数
+func profileSeconds(r *http.Request) (int, error) {
+	value := r.URL.Query().Get("seconds")
+	if value == "" {
+		return defaultProfileSeconds, nil
+	}
+	seconds, err := strconv.Atoi(value)
+	if err != nil || seconds <= 0 || seconds > maxProfileSeconds {
+		return 0, fmt.Errorf("seconds must be between 1 and %d", maxProfileSeconds)
+	}
+	return seconds, nil
+}
+
+// sleep 等待指定秒数，客户端断开时提前返回
+func sleep(ctx context.Context, seconds int) {
+	timer := time.NewTimer(time.Duration(seconds) * time.Second)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+}
@@ -0,0 +1,76 @@
+package debug
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"runtime/pprof"
+	"testing"
+
+	"ai-gatway/pkg/apierror"
+)
+
+func TestHandlerErrorEnvelope(t *testing.T) {
+	tests := []struct {
+		name   string
+		path   string
+		status int
+		code   apierror.Code
+	}{
+		{"unknown profile", "/debug/pprof/nope", http.StatusNotFound, apierror.CodeNotFound},
+		{"invalid profile seconds", "/debug/pprof/profile?seconds=abc", http.StatusBadRequest, apierror.CodeBadRequest},
+		{"trace seconds above limit", "/debug/pprof/trace?seconds=301", http.StatusBadRequest, apierror.CodeBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req.Header.Set(apierror.RequestIDHeader, "req-1")
+			rec := httptest.NewRecorder()
+			Handler().ServeHTTP(rec, req)
+			assertEnvelope(t, rec, tt.status, tt.code)
+		})
+	}
+}
+
+func TestCPUProfileConflict(t *testing.T) {
+	// 已有CPU profile在采集时返回409
+	if err := pprof.StartCPUProfile(io.Discard); err != nil {
+		t.Skipf("cannot start CPU profile: %v", err)
+	}
+	defer pprof.StopCPUProfile()
+
+	req := httptest.NewRequest(http.MethodGet, "/debug/pprof/profile?seconds=1", nil)
+	req.Header.Set(apierror.RequestIDHeader, "req-1")
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, req)
+	assertEnvelope(t, rec, http.StatusConflict, apierror.CodeConflict)
+	if rec.Header().Get("Content-Disposition") != "" {
+		t.Error("error response should not be served as an attachment")
+	}
+}
+
+func TestHandlerServesProfile(t *testing.T) {
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/pprof/goroutine?debug=1", nil))
+	if rec.Code != http.StatusOK || rec.Body.Len() == 0 {
+		t.Errorf("status = %d, body length = %d, want 200 with a profile", rec.Code, rec.Body.Len())
+	}
+}
+
+func assertEnvelope(t *testing.T, rec *httptest.ResponseRecorder, status int, code apierror.Code) {
+	t.Helper()
+	if rec.Code != status {
+		t.Errorf("status = %d, want %d", rec.Code, status)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", ct)
+	}
+	var envelope apierror.Envelope
+	if err := json.NewDecoder(rec.Body).Decode(&envelope); err != nil {
+		t.Fatalf("response is not an error envelope: %v", err)
+	}
+	if envelope.Error.Code != code || envelope.Error.RequestID != "req-1" || envelope.Error.Message == "" {
+		t.Errorf("envelope = %+v, want code %s with request_id req-1", envelope.Error, code)
+	}
+}
@@ -0,0 +1,255 @@
+package httpx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// 默认参数
+const (
+	defaultTimeout      = 30 * time.Second
+	defaultRetryBackoff = 200 * time.Millisecond
+	defaultMaxBackoff   = 5 * time.Second
+)
+
+// Config HTTP客户端配置
+type Config struct {
+	// Timeout 单次请求(含读取响应体)的超时时间，0表示使用默认值，负数表示不限制。
+	// 单次调用可以通过请求context的deadline设置更短的超时。
+	Timeout time.Duration
+	// MaxRetries 失败后的最大重试次数，0表示不重试
+	MaxRetries int
+	// RetryBackoff 首次重试前的等待时间，之后按指数增长
+	RetryBackoff time.Duration
+	// MaxBackoff 重试等待时间上限，Retry-After超过该值时不再重试
+	MaxBackoff time.Duration
+	// RetryNonIdempotent 为true时，带请求体的非幂等请求(如POST)也在超时、
+	// 连接中断和429/502/503/504时重试；默认只在连接未建立(拨号失败)时重试
+	RetryNonIdempotent bool
+	// RateLimit 每秒允许发出的请求数，0表示不限制
+	RateLimit float64
+	// Burst 限流时允许的突发请求数
+	Burst int
+	// Transport 底层传输层，nil时使用http.DefaultTransport
+	Transport http.RoundTripper
+	// Hook 出站请求钩子，用于调试日志等，nil表示不启用
+	Hook Hook
+}
+
+// Client 带超时、重试和客户端限流的HTTP客户端
+type Client struct {
+	client  *http.Client
+	config  Config
+	limiter *rate.Limiter
+}
+
+// New 创建HTTP客户端
+func New(config Config) *Client {
+	switch {
+	case config.Timeout == 0:
+		config.Timeout = defaultTimeout
+	case config.Timeout < 0:
+		config.Timeout = 0
+	}
+	if config.RetryBackoff <= 0 {
+		config.RetryBackoff = defaultRetryBackoff
+	}
+	if config.MaxBackoff <= 0 {
+		config.MaxBackoff = defaultMaxBackoff
+	}
+
+	c := &Client{
+		client: &http.Client{
+			Timeout:   config.Timeout,
+			Transport: config.Transport,
+		},
+		config: config,
+	}
+	if config.RateLimit > 0 {
+		burst := config.Burst
+		if burst <= 0 {
+			burst = 1
+		}
+		c.limiter = rate.NewLimiter(rate.Limit(config.RateLimit), burst)
+	}
+	return c
+}
+
+// Do 发送请求并按配置重试。
+// 幂等请求(GET、HEAD、OPTIONS、PUT、DELETE)在连接错误、超时和429/502/503/504响应时重试；
+// 非幂等请求默认只在拨号失败时重试，此时请求一定没有到达服务端。
+// 带请求体的请求只有在可以重放(GetBody不为nil)时才会重试。
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	canRetry := req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+	fullRetry := c.config.RetryNonIdempotent || isIdempotent(req.Method)
+
+	for attempt := 0; ; attempt++ {
+		if c.limiter != nil {
+			if err := c.limiter.Wait(ctx); err != nil {
+				return nil, err
+			}
+		}
+
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+
+		resp, err := c.send(req)
+		if !canRetry || attempt >= c.config.MaxRetries || ctx.Err() != nil {
+			return resp, err
+		}
+
+		var wait time.Duration
+		if err != nil {
+			if !isDialError(err) && !fullRetry {
+				return resp, err
+			}
+			wait = c.backoff(attempt)
+		} else {
+			if !fullRetry || !isRetryableStatus(resp.StatusCode) {
+				return resp, err
+			}
+			wait = c.backoff(attempt)
+			// 服务端要求的等待时间超过上限时直接返回响应，由调用方处理
+			if retryAfter, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+				if retryAfter > c.config.MaxBackoff {
+					return resp, err
+				}
+				wait = max(wait, retryAfter)
+			}
+
+			// 丢弃响应体以复用连接
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+			resp.Body.Close()
+		}
+
+		if err := sleep(ctx, wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// send 发送单次请求并调用钩子
+func (c *Client) send(req *http.Request) (*http.Response, error) {
+	hook := c.config.Hook
+	if hook == nil {
+		return c.client.Do(req)
+	}
+
+	hook.OnRequest(requestInfo(req))
+	start := time.Now()
+	resp, err := c.client.Do(req)
+
+	info := ResponseInfo{
+		Method:   req.Method,
+		URL:      req.URL.String(),
+		Duration: time.Since(start),
+		Err:      err,
+	}
+	if err != nil {
+		hook.OnResponse(info)
+		return resp, err
+	}
+
+	info.Status = resp.StatusCode
+	info.Header = resp.Header
+	if isStreaming(resp) {
+		// 流式响应只记录元数据
+		info.Streaming = true
+		hook.OnResponse(info)
+		return resp, nil
+	}
+
+	// 响应体在调用方读取并关闭后回调钩子
+	resp.Body = &hookedBody{ReadCloser: resp.Body, info: info, hook: hook}
+	return resp, nil
+}
+
+// Get 发送GET请求
+func (c *Client) Get(ctx context.Context, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	return c.Do(req)
+}
+
+// HTTPClient 返回底层的http.Client，供需要标准客户端的场景使用(不含重试和限流)
+func (c *Client) HTTPClient() *http.Client {
+	return c.client
+}
+
+// backoff 计算第attempt次失败后的等待时间
+func (c *Client) backoff(attempt int) time.Duration {
+	d := c.config.RetryBackoff << attempt
+	if d <= 0 || d > c.config.MaxBackoff {
+		return c.config.MaxBackoff
+	}
+	return d
+}
+
+// isIdempotent 判断请求方法是否幂等
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// isRetryableStatus 判断响应状态码是否值得重试
+func isRetryableStatus(status int) bool {
+	switch status {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// isDialError 判断错误是否发生在建立连接阶段(如连接被拒绝)，此时请求没有发出
+func isDialError(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// parseRetryAfter 解析Retry-After响应头，支持秒数和HTTP日期两种格式
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return max(at.Sub(now), 0), true
+	}
+	return 0, false
+}
+
+// sleep 等待指定时间，context取消时提前返回
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
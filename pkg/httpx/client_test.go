@@ -0,0 +1,175 @@
+package httpx
+
+import (
+	"bytes"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// roundTripFunc 用函数实现http.RoundTripper
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// countingServer 返回记录请求次数的测试服务
+func countingServer(t *testing.T, handler func(n int32, w http.ResponseWriter)) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler(hits.Add(1), w)
+	}))
+	t.Cleanup(server.Close)
+	return server, &hits
+}
+
+func post(t *testing.T, c *Client, url string) (*http.Response, error) {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewBufferString(`{"model":"m"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c.Do(req)
+}
+
+func TestPostRetriesDialErrors(t *testing.T) {
+	var attempts atomic.Int32
+	base := http.DefaultTransport
+	c := New(Config{
+		MaxRetries:   2,
+		RetryBackoff: time.Millisecond,
+		Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			if attempts.Add(1) == 1 {
+				return nil, &net.OpError{Op: "dial", Net: "tcp", Err: &net.DNSError{Err: "connection refused"}}
+			}
+			return base.RoundTrip(req)
+		}),
+	})
+	server, hits := countingServer(t, func(int32, http.ResponseWriter) {})
+
+	resp, err := post(t, c, server.URL)
+	if err != nil {
+		t.Fatalf("expected retry after dial error, got %v", err)
+	}
+	resp.Body.Close()
+	if attempts.Load() != 2 || hits.Load() != 1 {
+		t.Errorf("attempts = %d, server hits = %d, want 2 and 1", attempts.Load(), hits.Load())
+	}
+}
+
+func TestPostDoesNotRetryAfterTimeout(t *testing.T) {
+	server, hits := countingServer(t, func(int32, http.ResponseWriter) {
+		time.Sleep(100 * time.Millisecond)
+	})
+	c := New(Config{Timeout: 20 * time.Millisecond, MaxRetries: 3, RetryBackoff: time.Millisecond})
+
+	if _, err := post(t, c, server.URL); err == nil {
+		t.Fatal("expected timeout error")
+	}
+	if n := hits.Load(); n != 1 {
+		t.Errorf("server hits = %d, want 1 (timed out POST must not be resent)", n)
+	}
+}
+
+func TestRetryableStatus(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		nonIdem    bool
+		wantHits   int32
+		wantStatus int
+	}{
+		{"post is not retried", http.MethodPost, false, 1, http.StatusServiceUnavailable},
+		{"post retried when opted in", http.MethodPost, true, 2, http.StatusOK},
+		{"get is retried", http.MethodGet, false, 2, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, hits := countingServer(t, func(n int32, w http.ResponseWriter) {
+				if n == 1 {
+					w.WriteHeader(http.StatusServiceUnavailable)
+				}
+			})
+			c := New(Config{MaxRetries: 1, RetryBackoff: time.Millisecond, RetryNonIdempotent: tt.nonIdem})
+
+			req, _ := http.NewRequest(tt.method, server.URL, strings.NewReader("{}"))
+			resp, err := c.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.wantStatus || hits.Load() != tt.wantHits {
+				t.Errorf("status = %d, hits = %d, want %d and %d", resp.StatusCode, hits.Load(), tt.wantStatus, tt.wantHits)
+			}
+		})
+	}
+}
+
+func TestRetryAfterIsHonored(t *testing.T) {
+	server, hits := countingServer(t, func(n int32, w http.ResponseWriter) {
+		if n == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+		}
+	})
+	c := New(Config{MaxRetries: 1, RetryBackoff: time.Millisecond, MaxBackoff: 2 * time.Second})
+
+	start := time.Now()
+	resp, err := c.Get(t.Context(), server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("retried after %v, want at least the 1s Retry-After", elapsed)
+	}
+	if resp.StatusCode != http.StatusOK || hits.Load() != 2 {
+		t.Errorf("status = %d, hits = %d", resp.StatusCode, hits.Load())
+	}
+}
+
+func TestRetryAfterAboveMaxBackoffReturnsResponse(t *testing.T) {
+	server, hits := countingServer(t, func(n int32, w http.ResponseWriter) {
+		w.Header().Set("Retry-After", "120")
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+	c := New(Config{MaxRetries: 3, RetryBackoff: time.Millisecond, MaxBackoff: time.Second})
+
+	resp, err := c.Get(t.Context(), server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusTooManyRequests || hits.Load() != 1 {
+		t.Errorf("status = %d, hits = %d, want 429 without retry", resp.StatusCode, hits.Load())
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value  string
+		want   time.Duration
+		wantOK bool
+	}{
+		{"", 0, false},
+		{"5", 5 * time.Second, true},
+		{"0", 0, true},
+		{"-1", 0, false},
+		{"soon", 0, false},
+		{now.Add(30 * time.Second).Format(http.TimeFormat), 30 * time.Second, true},
+		{now.Add(-time.Minute).Format(http.TimeFormat), 0, true},
+	}
+	for _, tt := range tests {
+		got, ok := parseRetryAfter(tt.value, now)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("parseRetryAfter(%q) = %v, %v; want %v, %v", tt.value, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package httpx
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// maxHookBodyBytes 传给钩子的响应体最大字节数，避免大响应占用内存。
+// 请求体已在内存中，完整传给钩子，由钩子先脱敏再截断。
+const maxHookBodyBytes = 64 << 10
+
+// RequestInfo 出站请求信息
+type RequestInfo struct {
+	Method string
+	URL    string
+	Header http.Header
+	Body   []byte
+}
+
+// ResponseInfo 出站请求的响应信息，流式响应不包含Body
+type ResponseInfo struct {
+	Method    string
+	URL       string
+	Status    int
+	Duration  time.Duration
+	Header    http.Header
+	Body      []byte
+	Streaming bool
+	Err       error
+}
+
+// Hook 出站请求钩子，每次尝试(包括重试)都会调用
+type Hook interface {
+	OnRequest(info RequestInfo)
+	OnResponse(info ResponseInfo)
+}
+
+// sensitiveHeaders 需要屏蔽的请求头
+var sensitiveHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Api-Key"}
+
+// sensitiveFields 匹配JSON中名称类似api_key、password的字符串字段。
+// 值缺少结束引号时匹配到末尾，被截断的响应体中的敏感值同样会被屏蔽。
+var sensitiveFields = regexp.MustCompile(`(?i)("[a-z_\-]*(?:api[_\-]?key|password|passwd|secret|token|authorization)"\s*:\s*)"(?:[^"\\]|\\.)*(?:"|\\?$)`)
+
+// RedactHeaders 返回屏蔽敏感值后的请求头副本
+func RedactHeaders(header http.Header) http.Header {
+	redacted := header.Clone()
+	for _, name := range sensitiveHeaders {
+		if redacted.Get(name) != "" {
+			redacted.Set(name, "[REDACTED]")
+		}
+	}
+	return redacted
+}
+
+// RedactBody 屏蔽JSON请求/响应体中名称类似api_key、password、token的字段值
+func RedactBody(body []byte) []byte {
+	return sensitiveFields.ReplaceAll(body, []byte(`$1"[REDACTED]"`))
+}
+
+// LogHook 将出站请求记录到日志的钩子，请求体和响应体经过脱敏和截断
+type LogHook struct {
+	Logger logrus.FieldLogger
+	// MaxBodyBytes 日志中保留的请求/响应体字节数，0表示不记录请求体和响应体
+	MaxBodyBytes int
+	// Redact 请求体/响应体脱敏函数，nil时使用RedactBody
+	Redact func([]byte) []byte
+}
+
+// OnRequest 记录出站请求
+func (h *LogHook) OnRequest(info RequestInfo) {
+	fields := logrus.Fields{
+		"method":  info.Method,
+		"url":     info.URL,
+		"headers": RedactHeaders(info.Header),
+	}
+	if body := h.body(info.Body); body != "" {
+		fields["body"] = body
+	}
+	h.Logger.WithFields(fields).Debug("Outbound request")
+}
+
+// OnResponse 记录出站请求的响应
+func (h *LogHook) OnResponse(info ResponseInfo) {
+	fields := logrus.Fields{
+		"method":    info.Method,
+		"url":       info.URL,
+		"status":    info.Status,
+		"duration":  info.Duration.String(),
+		"streaming": info.Streaming,
+	}
+	if info.Err != nil {
+		h.Logger.WithFields(fields).WithError(info.Err).Debug("Outbound request failed")
+		return
+	}
+	if body := h.body(info.Body); body != "" {
+		fields["body"] = body
+	}
+	h.Logger.WithFields(fields).Debug("Outbound response")
+}
+
+// body 先脱敏再截断请求体/响应体，截断不会留下未屏蔽的敏感值片段
+func (h *LogHook) body(body []byte) string {
+	if h.MaxBodyBytes <= 0 || len(body) == 0 {
+		return ""
+	}
+	redact := h.Redact
+	if redact == nil {
+		redact = RedactBody
+	}
+	redacted := redact(body)
+	if len(redacted) > h.MaxBodyBytes {
+		return string(redacted[:h.MaxBodyBytes]) + "...(truncated)"
+	}
+	return string(redacted)
+}
+
+// requestInfo 收集请求信息，读取完整的可重放请求体副本
+func requestInfo(req *http.Request) RequestInfo {
+	info := RequestInfo{
+		Method: req.Method,
+		URL:    req.URL.String(),
+		Header: req.Header,
+	}
+	if req.GetBody != nil {
+		if body, err := req.GetBody(); err == nil {
+			info.Body, _ = io.ReadAll(body)
+			body.Close()
+		}
+	}
+	return info
+}
+
+// isStreaming 判断响应是否为流式响应
+func isStreaming(resp *http.Response) bool {
+	return strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream")
+}
+
+// hookedBody 在调用方读取响应体时截取内容，关闭时回调钩子
+type hookedBody struct {
+	io.ReadCloser
+	buf    bytes.Buffer
+	info   ResponseInfo
+	hook   Hook
+	closed bool
+}
+
+func (b *hookedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if remaining := maxHookBodyBytes - b.buf.Len(); remaining > 0 && n > 0 {
+		b.buf.Write(p[:min(n, remaining)])
+	}
+	return n, err
+}
+
+func (b *hookedBody) Close() error {
+	err := b.ReadCloser.Close()
+	if !b.closed {
+		b.closed = true
+		b.info.Body = b.buf.Bytes()
+		b.hook.OnResponse(b.info)
+	}
+	return err
+}
@@ -0,0 +1,141 @@
+package httpx
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+// seededSecret 植入请求/响应中的敏感值，不应出现在任何日志中
+const seededSecret = "sk-live-0123456789abcdef"
+
+func TestRedactHeaders(t *testing.T) {
+	header := http.Header{}
+	header.Set("Authorization", "Bearer "+seededSecret)
+	header.Set("X-Api-Key", seededSecret)
+	header.Set("Content-Type", "application/json")
+
+	redacted := RedactHeaders(header)
+	for _, name := range []string{"Authorization", "X-Api-Key"} {
+		if got := redacted.Get(name); got != "[REDACTED]" {
+			t.Errorf("%s = %q, want [REDACTED]", name, got)
+		}
+	}
+	if got := redacted.Get("Content-Type"); got != "application/json" {
+		t.Errorf("Content-Type = %q, want it unchanged", got)
+	}
+	if header.Get("Authorization") != "Bearer "+seededSecret {
+		t.Error("RedactHeaders modified the original header")
+	}
+}
+
+func TestRedactBody(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{"api key", `{"api_key":"` + seededSecret + `"}`, `{"api_key":"[REDACTED]"}`},
+		{"password with spaces", `{"password" : "` + seededSecret + `"}`, `{"password" : "[REDACTED]"}`},
+		{"case insensitive", `{"Access-Token":"` + seededSecret + `"}`, `{"Access-Token":"[REDACTED]"}`},
+		{"escaped quote", `{"secret":"a\"` + seededSecret + `"}`, `{"secret":"[REDACTED]"}`},
+		{"other fields kept", `{"model":"m","token":"` + seededSecret + `"}`, `{"model":"m","token":"[REDACTED]"}`},
+		{"cut inside value", `{"api_key":"` + seededSecret[:10], `{"api_key":"[REDACTED]"`},
+		{"cut after escape", `{"api_key":"ab\`, `{"api_key":"[REDACTED]"`},
+		{"not sensitive", `{"content":"hello"}`, `{"content":"hello"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(RedactBody([]byte(tt.body))); got != tt.want {
+				t.Errorf("RedactBody(%s) = %s, want %s", tt.body, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLogHookBody(t *testing.T) {
+	// 敏感值跨越截断位置：先截断再脱敏会泄露前半段
+	prefix := `{"messages":"` + strings.Repeat("x", 20) + `","api_key":"`
+	crossing := prefix + seededSecret + `"}`
+	long := `{"content":"` + strings.Repeat("a", 100) + `"}`
+
+	tests := []struct {
+		name    string
+		max     int
+		body    string
+		want    string
+		notWant string
+	}{
+		{"disabled", 0, long, "", ""},
+		{"short body kept", 1000, `{"model":"m"}`, `{"model":"m"}`, ""},
+		{"truncated", 20, long, long[:20] + "...(truncated)", ""},
+		{"secret crossing cut", len(prefix) + 4, crossing, "", seededSecret[:4]},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &LogHook{MaxBodyBytes: tt.max}
+			got := h.body([]byte(tt.body))
+			if tt.want != "" && got != tt.want {
+				t.Errorf("body = %q, want %q", got, tt.want)
+			}
+			if tt.max == 0 && got != "" {
+				t.Errorf("body = %q, want empty when MaxBodyBytes is 0", got)
+			}
+			if tt.notWant != "" && strings.Contains(got, tt.notWant) {
+				t.Errorf("body %q leaks part of the secret", got)
+			}
+		})
+	}
+}
+
+func TestLogHookRedactsOutboundTraffic(t *testing.T) {
+	// 响应体中的敏感值跨越钩子截取上限
+	// 前缀长度为maxHookBodyBytes-8，截取的内容以敏感值的前8个字节结尾
+	padding := strings.Repeat("x", maxHookBodyBytes-28)
+	respBody := `{"data":"` + padding + `","token":"` + seededSecret + `"}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, respBody)
+	}))
+	defer server.Close()
+
+	logger, logs := test.NewNullLogger()
+	logger.SetLevel(logrus.DebugLevel)
+	c := New(Config{Hook: &LogHook{Logger: logger, MaxBodyBytes: 1 << 20}})
+
+	reqBody := `{"prompt":"` + strings.Repeat("p", maxHookBodyBytes) + `","api_key":"` + seededSecret + `"}`
+	req, err := http.NewRequest(http.MethodPost, server.URL, bytes.NewBufferString(reqBody))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+seededSecret)
+	resp, err := c.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	entries := logs.AllEntries()
+	if len(entries) != 2 {
+		t.Fatalf("got %d log entries, want request and response", len(entries))
+	}
+	for _, entry := range entries {
+		for key, value := range entry.Data {
+			if strings.Contains(fmt.Sprint(value), seededSecret[:8]) {
+				t.Errorf("%q field %q leaks the secret", entry.Message, key)
+			}
+		}
+	}
+	if body := fmt.Sprint(entries[0].Data["body"]); !strings.Contains(body, `"api_key":"[REDACTED]"`) {
+		t.Error("request body beyond the response capture limit was not redacted")
+	}
+}
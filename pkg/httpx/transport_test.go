@@ -0,0 +1,112 @@
+package httpx
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// countDials 包装传输层的DialContext以统计建立的连接数
+func countDials(transport *http.Transport) *atomic.Int64 {
+	var dials atomic.Int64
+	dial := transport.DialContext
+	transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		dials.Add(1)
+		return dial(ctx, network, addr)
+	}
+	return &dials
+}
+
+// get 发送请求并读完响应体，使连接可以复用
+func get(tb testing.TB, c *Client, url string) {
+	tb.Helper()
+	resp, err := c.Get(context.Background(), url)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+}
+
+func newEchoServer(tb testing.TB) *httptest.Server {
+	tb.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	tb.Cleanup(server.Close)
+	return server
+}
+
+func TestSharedTransportReusesConnections(t *testing.T) {
+	server := newEchoServer(t)
+	transport := NewTransport(TransportConfig{})
+	dials := countDials(transport)
+
+	// 两个客户端共享同一个传输层
+	a := New(Config{Transport: transport})
+	b := New(Config{Transport: transport})
+	for i := 0; i < 10; i++ {
+		get(t, a, server.URL)
+		get(t, b, server.URL)
+	}
+	if n := dials.Load(); n != 1 {
+		t.Errorf("20 sequential requests dialed %d times, want 1", n)
+	}
+}
+
+func TestConcurrentRequestsStayWithinIdlePool(t *testing.T) {
+	server := newEchoServer(t)
+	transport := NewTransport(TransportConfig{MaxIdleConnsPerHost: 8, MaxConnsPerHost: 8})
+	dials := countDials(transport)
+	c := New(Config{Transport: transport})
+
+	for round := 0; round < 5; round++ {
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				get(t, c, server.URL)
+			}()
+		}
+		wg.Wait()
+	}
+	if n := dials.Load(); n > 8 {
+		t.Errorf("40 requests with 8-way concurrency dialed %d times, want at most 8", n)
+	}
+}
+
+// BenchmarkTransportDials 报告每个请求的平均建连次数(dials/op)，连接池生效时接近0
+func BenchmarkTransportDials(b *testing.B) {
+	server := newEchoServer(b)
+	for _, bc := range []struct {
+		name      string
+		transport func() *http.Transport
+	}{
+		{"shared", func() *http.Transport { return NewTransport(TransportConfig{}) }},
+		{"no-keepalive", func() *http.Transport {
+			transport := NewTransport(TransportConfig{})
+			transport.DisableKeepAlives = true
+			return transport
+		}},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			transport := bc.transport()
+			dials := countDials(transport)
+			c := New(Config{Transport: transport})
+
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					get(b, c, server.URL)
+				}
+			})
+			b.ReportMetric(float64(dials.Load())/float64(b.N), "dials/op")
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package logger
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Config 日志配置
+type Config struct {
+	// Service 服务名称，作为每条日志的service字段
+	Service string
+	// Level 日志级别: debug, info, warn, error
+	Level string
+	// Format 输出格式: text或json
+	Format string
+	// File 日志文件路径，为空时只输出到标准输出
+	File string
+}
+
+// Validate 检查日志级别和格式是否有效
+func (c Config) Validate() error {
+	if _, err := c.level(); err != nil {
+		return err
+	}
+	switch c.Format {
+	case "", "text", "json":
+		return nil
+	default:
+		return fmt.Errorf("invalid log format %q, expected text or json", c.Format)
+	}
+}
+
+// level 解析日志级别，未设置时为info
+func (c Config) level() (logrus.Level, error) {
+	if c.Level == "" {
+		return logrus.InfoLevel, nil
+	}
+	level, err := logrus.ParseLevel(c.Level)
+	if err != nil {
+		return level, fmt.Errorf("invalid log level %q: %v", c.Level, err)
+	}
+	return level, nil
+}
+
+// New 根据配置创建带service字段的日志记录器
+func New(cfg Config) (*logrus.Entry, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	log := logrus.New()
+	level, _ := cfg.level()
+	log.SetLevel(level)
+
+	if cfg.Format == "json" {
+		log.SetFormatter(&logrus.JSONFormatter{})
+	} else {
+		log.SetFormatter(&logrus.TextFormatter{FullTimestamp: true})
+	}
+
+	log.SetOutput(os.Stdout)
+	if cfg.File != "" {
+		if err := os.MkdirAll(filepath.Dir(cfg.File), 0755); err != nil {
+			return nil, fmt.Errorf("failed to create log directory: %v", err)
+		}
+		file, err := os.OpenFile(cfg.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open log file: %v", err)
+		}
+		log.SetOutput(io.MultiWriter(os.Stdout, file))
+	}
+
+	return log.WithField("service", cfg.Service), nil
+}
@@ -0,0 +1,103 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// useRecorder 安装写入内存导出器的TracerProvider
+func useRecorder(t *testing.T, ratio float64) *tracetest.InMemoryExporter {
+	t.Helper()
+	exporter := tracetest.NewInMemoryExporter()
+	provider := newProvider("test", ratio, sdktrace.WithSyncer(exporter))
+	previous := otel.GetTracerProvider()
+	setPropagator()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() {
+		provider.Shutdown(context.Background())
+		otel.SetTracerProvider(previous)
+	})
+	return exporter
+}
+
+func TestSpansPropagateAcrossServices(t *testing.T) {
+	exporter := useRecorder(t, 1)
+
+	backend := httptest.NewServer(Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}), "backend"))
+	defer backend.Close()
+
+	client := &http.Client{Transport: Transport(nil)}
+	frontend := httptest.NewServer(Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req, _ := http.NewRequestWithContext(r.Context(), http.MethodGet, backend.URL, nil)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		resp.Body.Close()
+	}), "frontend"))
+	defer frontend.Close()
+
+	resp, err := http.Get(frontend.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	spans := exporter.GetSpans()
+	if len(spans) != 3 {
+		t.Fatalf("got %d spans, want server, client and server", len(spans))
+	}
+
+	var frontendSpan, clientSpan, backendSpan tracetest.SpanStub
+	for _, span := range spans {
+		switch {
+		case span.SpanKind == trace.SpanKindClient:
+			clientSpan = span
+		case span.Name == "frontend":
+			frontendSpan = span
+		case span.Name == "backend":
+			backendSpan = span
+		}
+	}
+
+	traceID := frontendSpan.SpanContext.TraceID()
+	for _, span := range []tracetest.SpanStub{clientSpan, backendSpan} {
+		if span.SpanContext.TraceID() != traceID {
+			t.Errorf("span %s has trace %s, want %s", span.Name, span.SpanContext.TraceID(), traceID)
+		}
+	}
+	if clientSpan.Parent.SpanID() != frontendSpan.SpanContext.SpanID() {
+		t.Error("client span is not a child of the frontend server span")
+	}
+	if backendSpan.Parent.SpanID() != clientSpan.SpanContext.SpanID() {
+		t.Error("backend server span is not a child of the client span")
+	}
+}
+
+func TestZeroSampleRatioRecordsNothing(t *testing.T) {
+	exporter := useRecorder(t, 0)
+
+	handler := Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}), "op")
+	for i := 0; i < 20; i++ {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	}
+	if n := len(exporter.GetSpans()); n != 0 {
+		t.Errorf("sample_ratio 0 recorded %d spans", n)
+	}
+}
+
+func TestInitRejectsInvalidSampleRatio(t *testing.T) {
+	for _, ratio := range []float64{-0.1, 1.5} {
+		if _, err := Init(context.Background(), "test", Config{Enabled: true, Endpoint: "localhost:4318", SampleRatio: ratio}); err == nil {
+			t.Errorf("sample ratio %v accepted", ratio)
+		}
+	}
+}
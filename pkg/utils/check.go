@@ -0,0 +1,97 @@
+package utils
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// ConfigReport 配置自检结果
+type ConfigReport struct {
+	Errors   []string
+	Warnings []string
+	Checks   []string
+}
+
+// Errorf 记录一个会导致启动失败的问题
+func (r *ConfigReport) Errorf(format string, args ...interface{}) {
+	r.Errors = append(r.Errors, fmt.Sprintf(format, args...))
+}
+
+// Warnf 记录一个不影响启动的问题
+func (r *ConfigReport) Warnf(format string, args ...interface{}) {
+	r.Warnings = append(r.Warnings, fmt.Sprintf(format, args...))
+}
+
+// Passf 记录一项通过的检查
+func (r *ConfigReport) Passf(format string, args ...interface{}) {
+	r.Checks = append(r.Checks, fmt.Sprintf(format, args...))
+}
+
+// OK 没有错误时返回true
+func (r *ConfigReport) OK() bool {
+	return len(r.Errors) == 0
+}
+
+// Print 输出可读的自检报告
+func (r *ConfigReport) Print(w io.Writer, service string) {
+	fmt.Fprintf(w, "Configuration check for %s\n", service)
+	for _, check := range r.Checks {
+		fmt.Fprintf(w, "  [ok]    %s\n", check)
+	}
+	for _, warning := range r.Warnings {
+		fmt.Fprintf(w, "  [warn]  %s\n", warning)
+	}
+	for _, err := range r.Errors {
+		fmt.Fprintf(w, "  [error] %s\n", err)
+	}
+	if r.OK() {
+		fmt.Fprintf(w, "Result: OK (%d warnings)\n", len(r.Warnings))
+	} else {
+		fmt.Fprintf(w, "Result: FAILED (%d errors, %d warnings)\n", len(r.Errors), len(r.Warnings))
+	}
+}
+
+// CheckConfigFile 检查配置文件能否读取，供各服务自检使用
+func CheckConfigFile(report *ConfigReport) bool {
+	config, err := LoadConfig()
+	if err != nil {
+		report.Errorf("%v", err)
+		return false
+	}
+	report.Passf("loaded config file %s", config.ConfigFileUsed())
+	return true
+}
+
+// CheckMappingList 检查配置项是否为映射列表(如mcp.workers、gateway.routes)。
+// 类型错误时对应的读取函数会返回空列表，这里记录真正的原因；未设置时返回true。
+func CheckMappingList(report *ConfigReport, key string) bool {
+	config, _ := LoadConfig()
+	if !config.IsSet(key) {
+		return true
+	}
+
+	list, ok := config.Get(key).([]interface{})
+	if !ok {
+		report.Errorf("%s must be a list, got %T", key, config.Get(key))
+		return false
+	}
+	valid := true
+	for i, item := range list {
+		if _, ok := item.(map[string]interface{}); !ok {
+			report.Errorf("%s[%d] must be a mapping, got %T", key, i, item)
+			valid = false
+		}
+	}
+	return valid
+}
+
+// CheckDuration 检查时长配置项能否解析，数字按unit计，未设置时返回true
+func CheckDuration(report *ConfigReport, key string, unit time.Duration) bool {
+	config, _ := LoadConfig()
+	if _, err := parseConfigDuration(config.Get(key), unit); err != nil {
+		report.Errorf("%s: %v", key, err)
+		return false
+	}
+	return true
+}
@@ -2,20 +2,27 @@ package utils
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 
+	"github.com/spf13/cast"
 	"github.com/spf13/viper"
 )
 
 var (
-	config     *viper.Viper
-	configOnce sync.Once
+	configMu  sync.Mutex
+	config    *viper.Viper
+	configErr error
 )
 
-// LoadConfig 加载并返回配置实例
+// LoadConfig 加载并返回配置实例，首次调用时按默认路径查找config.yaml
 func LoadConfig() (*viper.Viper, error) {
-	var err error
-	configOnce.Do(func() {
+	configMu.Lock()
+	defer configMu.Unlock()
+
+	if config == nil {
 		config = viper.New()
 		config.SetConfigName("config")
 		config.SetConfigType("yaml")
@@ -23,13 +30,56 @@ func LoadConfig() (*viper.Viper, error) {
 		config.AddConfigPath("../configs")
 		config.AddConfigPath("../../configs")
 
-		if err = config.ReadInConfig(); err != nil {
-			err = fmt.Errorf("failed to read config: %v", err)
-			return
+		if err := config.ReadInConfig(); err != nil {
+			configErr = fmt.Errorf("failed to read config: %v", err)
 		}
-	})
+	}
+
+	return config, configErr
+}
 
-	return config, err
+// SetConfigFile 从指定文件加载配置(各服务的--config参数)，替换按默认路径查找到的配置
+func SetConfigFile(path string) error {
+	v := viper.New()
+	v.SetConfigFile(path)
+	if err := v.ReadInConfig(); err != nil {
+		return fmt.Errorf("failed to read config: %v", err)
+	}
+
+	configMu.Lock()
+	defer configMu.Unlock()
+	config = v
+	configErr = nil
+	return nil
+}
+
+// parseConfigDuration 解析时长配置项：数字按unit计(兼容旧配置)，字符串按ParseDuration解析(如"30s"、"1d")
+func parseConfigDuration(value interface{}, unit time.Duration) (time.Duration, error) {
+	switch v := value.(type) {
+	case nil:
+		return 0, nil
+	case string:
+		v = strings.TrimSpace(v)
+		if n, err := strconv.ParseFloat(v, 64); err == nil {
+			return time.Duration(n * float64(unit)), nil
+		}
+		return ParseDuration(v)
+	default:
+		n, err := cast.ToFloat64E(v)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %v", value)
+		}
+		return time.Duration(n * float64(unit)), nil
+	}
+}
+
+// getDuration 读取时长配置项，无法解析时返回0(使用默认值，由--validate-config报告)
+func getDuration(config *viper.Viper, key string, unit time.Duration) time.Duration {
+	d, err := parseConfigDuration(config.Get(key), unit)
+	if err != nil {
+		return 0
+	}
+	return d
 }
 
 // Worker 表示模型工作节点配置
@@ -49,6 +99,7 @@ type ModelInfo struct {
 	Description   string
 	ContextLength int
 	Capabilities  []string
+	SystemPrompt  string
 }
 
 // Route 路由信息
@@ -56,6 +107,7 @@ type Route struct {
 	Path         string
 	Target       string
 	AuthRequired bool
+	Streaming    bool
 }
 
 // ConsulConfig Consul配置
@@ -86,13 +138,13 @@ func GetMCPConfig() (port int, logLevel string, workers []Worker) {
 	if err := config.UnmarshalKey("mcp.workers", &workerConfigs); err == nil {
 		for _, wc := range workerConfigs {
 			worker := Worker{
-				Name:      wc["name"].(string),
-				URL:       wc["url"].(string),
-				Model:     wc["model"].(string),
-				Priority:  int(wc["priority"].(int64)),
-				MaxTokens: int(wc["max_tokens"].(int64)),
-				Timeout:   int(wc["timeout"].(int64)),
-				Streaming: wc["streaming"].(bool),
+				Name:      cast.ToString(wc["name"]),
+				URL:       cast.ToString(wc["url"]),
+				Model:     cast.ToString(wc["model"]),
+				Priority:  cast.ToInt(wc["priority"]),
+				MaxTokens: cast.ToInt(wc["max_tokens"]),
+				Timeout:   cast.ToInt(wc["timeout"]),
+				Streaming: cast.ToBool(wc["streaming"]),
 			}
 			workers = append(workers, worker)
 		}
@@ -101,6 +153,54 @@ func GetMCPConfig() (port int, logLevel string, workers []Worker) {
 	return config.GetInt("mcp.port"), config.GetString("mcp.log_level"), workers
 }
 
+// GetMCPHealthConfig 获取MCP工作节点健康检查配置(数字单位为秒)
+func GetMCPHealthConfig() (timeout, cacheTTL, interval time.Duration) {
+	config, _ := LoadConfig()
+	return getDuration(config, "mcp.health_check.timeout", time.Second),
+		getDuration(config, "mcp.health_check.cache_ttl", time.Second),
+		getDuration(config, "mcp.health_check.interval", time.Second)
+}
+
+// GetMCPLimitsConfig 获取MCP聊天请求体和上游响应体的大小上限(单位: 字节)
+func GetMCPLimitsConfig() (maxRequestBytes, maxResponseBytes int64) {
+	config, _ := LoadConfig()
+	return config.GetInt64("mcp.max_request_bytes"), config.GetInt64("mcp.max_response_bytes")
+}
+
+// GetHTTPClientConfig 获取出站HTTP客户端的重试和限流配置
+func GetHTTPClientConfig() (maxRetries int, retryBackoffMs int, rateLimit float64, burst int) {
+	config, _ := LoadConfig()
+	return config.GetInt("http_client.max_retries"),
+		config.GetInt("http_client.retry_backoff_ms"),
+		config.GetFloat64("http_client.rate_limit"),
+		config.GetInt("http_client.burst")
+}
+
+// GetHTTPClientDebugConfig 获取出站请求调试日志配置
+func GetHTTPClientDebugConfig() (debug bool, maxBodyBytes int) {
+	config, _ := LoadConfig()
+	return config.GetBool("http_client.debug"), config.GetInt("http_client.debug_max_body_bytes")
+}
+
+// HTTPTransportConfig 出站HTTP连接池配置，超时单位为秒
+type HTTPTransportConfig struct {
+	MaxIdleConns        int  `mapstructure:"max_idle_conns"`
+	MaxIdleConnsPerHost int  `mapstructure:"max_idle_conns_per_host"`
+	MaxConnsPerHost     int  `mapstructure:"max_conns_per_host"`
+	IdleConnTimeout     int  `mapstructure:"idle_conn_timeout"`
+	DialTimeout         int  `mapstructure:"dial_timeout"`
+	TLSHandshakeTimeout int  `mapstructure:"tls_handshake_timeout"`
+	DisableHTTP2        bool `mapstructure:"disable_http2"`
+}
+
+// GetHTTPTransportConfig 获取出站HTTP连接池配置
+func GetHTTPTransportConfig() HTTPTransportConfig {
+	config, _ := LoadConfig()
+	var transport HTTPTransportConfig
+	config.UnmarshalKey("http_client.transport", &transport)
+	return transport
+}
+
 // GetGatewayConfig 获取网关配置
 func GetGatewayConfig() (port int, logLevel, targetURL string, routes []Route) {
 	config, _ := LoadConfig()
@@ -110,9 +210,10 @@ func GetGatewayConfig() (port int, logLevel, targetURL string, routes []Route) {
 	if err := config.UnmarshalKey("gateway.routes", &routeConfigs); err == nil {
 		for _, rc := range routeConfigs {
 			route := Route{
-				Path:         rc["path"].(string),
-				Target:       rc["target"].(string),
-				AuthRequired: rc["auth_required"].(bool),
+				Path:         cast.ToString(rc["path"]),
+				Target:       cast.ToString(rc["target"]),
+				AuthRequired: cast.ToBool(rc["auth_required"]),
+				Streaming:    cast.ToBool(rc["streaming"]),
 			}
 			routes = append(routes, route)
 		}
@@ -124,13 +225,56 @@ func GetGatewayConfig() (port int, logLevel, targetURL string, routes []Route) {
 		routes
 }
 
-// GetAuthConfig 获取认证服务配置
-func GetAuthConfig() (port int, logLevel, jwtSecret string, tokenExpiry int) {
+// GetGatewayProxyConfig 获取网关反向代理超时配置(数字单位为秒)
+func GetGatewayProxyConfig() (dialTimeout, responseHeaderTimeout time.Duration) {
+	config, _ := LoadConfig()
+	return getDuration(config, "gateway.proxy.dial_timeout", time.Second),
+		getDuration(config, "gateway.proxy.response_header_timeout", time.Second)
+}
+
+// GetGatewayServerConfig 获取网关HTTP服务配置，超时数字单位为秒，请求体上限单位为字节
+func GetGatewayServerConfig() (readTimeout, writeTimeout time.Duration, maxBodyBytes int64) {
+	config, _ := LoadConfig()
+	return getDuration(config, "gateway.server.read_timeout", time.Second),
+		getDuration(config, "gateway.server.write_timeout", time.Second),
+		config.GetInt64("gateway.server.max_body_bytes")
+}
+
+// TLSClientConfig 网关访问后端时使用的TLS客户端配置
+type TLSClientConfig struct {
+	Host       string `mapstructure:"host"`
+	CertFile   string `mapstructure:"cert_file"`
+	KeyFile    string `mapstructure:"key_file"`
+	CAFile     string `mapstructure:"ca_file"`
+	ServerName string `mapstructure:"server_name"`
+}
+
+// GetGatewayTLSConfig 获取网关到后端的mTLS配置，返回默认配置和按目标(host:port)覆盖的配置。
+// 缺少host的条目以空字符串为键返回，由调用方拒绝。
+func GetGatewayTLSConfig() (enabled bool, defaults TLSClientConfig, targets map[string]TLSClientConfig) {
+	config, _ := LoadConfig()
+
+	enabled = config.GetBool("gateway.tls.enabled")
+	config.UnmarshalKey("gateway.tls", &defaults)
+
+	targets = make(map[string]TLSClientConfig)
+	var targetConfigs []TLSClientConfig
+	if err := config.UnmarshalKey("gateway.tls.targets", &targetConfigs); err == nil {
+		for _, tc := range targetConfigs {
+			targets[tc.Host] = tc
+		}
+	}
+
+	return enabled, defaults, targets
+}
+
+// GetAuthConfig 获取认证服务配置(token_expiry数字单位为秒)
+func GetAuthConfig() (port int, logLevel, jwtSecret string, tokenExpiry time.Duration) {
 	config, _ := LoadConfig()
 	return config.GetInt("auth.port"),
 		config.GetString("auth.log_level"),
 		config.GetString("auth.jwt_secret"),
-		config.GetInt("auth.token_expiry")
+		getDuration(config, "auth.token_expiry", time.Second)
 }
 
 // GetModelsConfig 获取模型配置
@@ -146,17 +290,45 @@ func GetModelsConfig() map[string]ModelInfo {
 		var capabilities []string
 		if caps, ok := modelMap["capabilities"].([]interface{}); ok {
 			for _, cap := range caps {
-				capabilities = append(capabilities, cap.(string))
+				capabilities = append(capabilities, cast.ToString(cap))
 			}
 		}
 
 		models[modelID] = ModelInfo{
-			Name:          modelMap["name"].(string),
-			Description:   modelMap["description"].(string),
-			ContextLength: int(modelMap["context_length"].(int64)),
+			Name:          cast.ToString(modelMap["name"]),
+			Description:   cast.ToString(modelMap["description"]),
+			ContextLength: cast.ToInt(modelMap["context_length"]),
 			Capabilities:  capabilities,
+			SystemPrompt:  cast.ToString(modelMap["system_prompt"]),
 		}
 	}
 
 	return models
 }
+
+// GetTracingConfig 获取链路追踪配置，未设置sample_ratio时全部采样
+func GetTracingConfig() (enabled bool, endpoint string, insecure bool, sampleRatio float64) {
+	config, _ := LoadConfig()
+	sampleRatio = 1
+	if config.IsSet("tracing.sample_ratio") {
+		sampleRatio = config.GetFloat64("tracing.sample_ratio")
+	}
+	return config.GetBool("tracing.enabled"),
+		config.GetString("tracing.otlp_endpoint"),
+		config.GetBool("tracing.insecure"),
+		sampleRatio
+}
+
+// GetLogConfig 获取指定服务(gateway、auth、mcp)的日志配置
+func GetLogConfig(service string) (level, format, file string) {
+	config, _ := LoadConfig()
+	return config.GetString(service + ".log_level"),
+		config.GetString(service + ".log_format"),
+		config.GetString(service + ".log_file")
+}
+
+// GetPprofConfig 获取指定服务(gateway、mcp)的性能分析调试端口配置
+func GetPprofConfig(service string) (enabled bool, addr string) {
+	config, _ := LoadConfig()
+	return config.GetBool(service + ".pprof.enabled"), config.GetString(service + ".pprof.addr")
+}
@@ -0,0 +1,128 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// writeConfig 将配置写入临时文件并返回路径
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestSetConfigFile(t *testing.T) {
+	if err := SetConfigFile(writeConfig(t, "auth:\n  port: 9001\n")); err != nil {
+		t.Fatal(err)
+	}
+	if port, _, _, _ := GetAuthConfig(); port != 9001 {
+		t.Errorf("auth.port = %d, want 9001", port)
+	}
+
+	// 加载失败时保留之前的配置
+	if err := SetConfigFile(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Fatal("expected an error for a missing config file")
+	}
+	if port, _, _, _ := GetAuthConfig(); port != 9001 {
+		t.Errorf("auth.port = %d after a failed reload, want 9001", port)
+	}
+}
+
+func TestSetConfigFileConcurrentWithReads(t *testing.T) {
+	paths := []string{
+		writeConfig(t, "auth:\n  port: 9001\n"),
+		writeConfig(t, "auth:\n  port: 9002\n"),
+	}
+	if err := SetConfigFile(paths[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			if err := SetConfigFile(paths[i%2]); err != nil {
+				t.Error(err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			if port, _, _, _ := GetAuthConfig(); port != 9001 && port != 9002 {
+				t.Errorf("auth.port = %d", port)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestGetGatewayTLSConfigTargets(t *testing.T) {
+	content := `
+gateway:
+  tls:
+    enabled: true
+    ca_file: /etc/ca.pem
+    targets:
+      - host: "worker:5000"
+        ca_file: /etc/worker-ca.pem
+      - server_name: missing-host
+`
+	if err := SetConfigFile(writeConfig(t, content)); err != nil {
+		t.Fatal(err)
+	}
+
+	enabled, defaults, targets := GetGatewayTLSConfig()
+	if !enabled || defaults.CAFile != "/etc/ca.pem" {
+		t.Errorf("enabled = %v, defaults = %+v", enabled, defaults)
+	}
+	if targets["worker:5000"].CAFile != "/etc/worker-ca.pem" {
+		t.Errorf("worker target = %+v", targets["worker:5000"])
+	}
+	// 缺少host的条目保留下来，由配置校验拒绝
+	if tc, ok := targets[""]; !ok || tc.ServerName != "missing-host" {
+		t.Errorf("entry without host = %+v, %v; want it kept under the empty key", tc, ok)
+	}
+}
+
+func TestDurationGetters(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  time.Duration
+	}{
+		{"plain seconds", "30", 30 * time.Second},
+		{"fractional seconds", "1.5", 1500 * time.Millisecond},
+		{"quoted number", `"45"`, 45 * time.Second},
+		{"go duration", `"500ms"`, 500 * time.Millisecond},
+		{"day unit", `"1d"`, 24 * time.Hour},
+		{"mixed units", `"1w2d"`, 9 * 24 * time.Hour},
+		{"unset", "", 0},
+		{"unparsable", `"soon"`, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := "auth:\n  port: 9001\n"
+			if tt.value != "" {
+				config += "  token_expiry: " + tt.value + "\n"
+			}
+			if err := SetConfigFile(writeConfig(t, config)); err != nil {
+				t.Fatal(err)
+			}
+			if _, _, _, got := GetAuthConfig(); got != tt.want {
+				t.Errorf("token_expiry = %v, want %v", got, tt.want)
+			}
+
+			report := &ConfigReport{}
+			if ok := CheckDuration(report, "auth.token_expiry", time.Second); ok != (tt.name != "unparsable") {
+				t.Errorf("CheckDuration = %v, errors %v", ok, report.Errors)
+			}
+		})
+	}
+}
@@ -0,0 +1,154 @@
+package utils
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 扩展的时间单位
+const (
+	Day  = 24 * time.Hour
+	Week = 7 * Day
+)
+
+// durationUnits 支持的时间单位，按从大到小排列(FormatDurationShort依赖该顺序)
+var durationUnits = []struct {
+	name string
+	unit time.Duration
+}{
+	{"w", Week},
+	{"d", Day},
+	{"h", time.Hour},
+	{"m", time.Minute},
+	{"s", time.Second},
+	{"ms", time.Millisecond},
+	{"us", time.Microsecond},
+	{"ns", time.Nanosecond},
+}
+
+// unitByName 单位名称到时长的映射(额外支持"µs"和"μs")
+var unitByName = func() map[string]time.Duration {
+	m := map[string]time.Duration{"µs": time.Microsecond, "μs": time.Microsecond}
+	for _, u := range durationUnits {
+		m[u.name] = u.unit
+	}
+	return m
+}()
+
+// ParseDuration 解析时长字符串，在time.ParseDuration的基础上支持天(d)和周(w)，
+// 例如"1w2d"、"1.5d"、"0.5d12h"、"-3h"。单位可以组合且可以是小数；
+// 除"0"外每个数字都必须带单位。
+func ParseDuration(s string) (time.Duration, error) {
+	orig := s
+	if s == "" {
+		return 0, fmt.Errorf("invalid duration: empty string")
+	}
+
+	neg := false
+	if s[0] == '-' || s[0] == '+' {
+		neg = s[0] == '-'
+		s = s[1:]
+	}
+	if s == "0" {
+		return 0, nil
+	}
+	if s == "" {
+		return 0, fmt.Errorf("invalid duration %q: missing value", orig)
+	}
+
+	var total uint64
+	for s != "" {
+		// 数字部分(整数和小数分开处理，避免大数值丢失精度)
+		i := 0
+		for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+			i++
+		}
+		intPart := s[:i]
+		fracPart := ""
+		if i < len(s) && s[i] == '.' {
+			j := i + 1
+			for j < len(s) && s[j] >= '0' && s[j] <= '9' {
+				j++
+			}
+			fracPart = s[i+1 : j]
+			i = j
+		}
+		if intPart == "" && fracPart == "" {
+			return 0, fmt.Errorf("invalid duration %q: expected number before %q", orig, s)
+		}
+		s = s[i:]
+
+		// 单位部分
+		j := 0
+		for j < len(s) && s[j] != '.' && (s[j] < '0' || s[j] > '9') {
+			j++
+		}
+		if j == 0 {
+			return 0, fmt.Errorf("invalid duration %q: missing unit", orig)
+		}
+		unit, ok := unitByName[s[:j]]
+		if !ok {
+			return 0, fmt.Errorf("invalid duration %q: unknown unit %q", orig, s[:j])
+		}
+		s = s[j:]
+
+		var value uint64
+		if intPart != "" {
+			n, err := strconv.ParseUint(intPart, 10, 64)
+			if err != nil || n > (1<<63)/uint64(unit) {
+				return 0, fmt.Errorf("invalid duration %q: overflow", orig)
+			}
+			value = n * uint64(unit)
+		}
+		if fracPart != "" {
+			frac, err := strconv.ParseFloat("0."+fracPart, 64)
+			if err != nil {
+				return 0, fmt.Errorf("invalid duration %q: bad number", orig)
+			}
+			value += uint64(math.Round(frac * float64(unit)))
+		}
+
+		total += value
+		if total > 1<<63 {
+			return 0, fmt.Errorf("invalid duration %q: overflow", orig)
+		}
+	}
+
+	if neg {
+		return -time.Duration(total), nil
+	}
+	if total > math.MaxInt64 {
+		return 0, fmt.Errorf("invalid duration %q: overflow", orig)
+	}
+	return time.Duration(total), nil
+}
+
+// FormatDurationShort 将时长格式化为ParseDuration可解析的紧凑形式，例如"1w2d3h"、"1m30s"
+func FormatDurationShort(d time.Duration) string {
+	if d == 0 {
+		return "0s"
+	}
+
+	var b strings.Builder
+	// 使用无符号值避免math.MinInt64取反溢出
+	u := uint64(d)
+	if d < 0 {
+		b.WriteByte('-')
+		u = -u
+	}
+
+	for _, unit := range durationUnits {
+		n := u / uint64(unit.unit)
+		if n == 0 {
+			continue
+		}
+		u -= n * uint64(unit.unit)
+		b.WriteString(strconv.FormatUint(n, 10))
+		b.WriteString(unit.name)
+	}
+
+	return b.String()
+}
@@ -0,0 +1,137 @@
+package utils
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		in   string
+		want time.Duration
+	}{
+		{"0", 0},
+		{"+0", 0},
+		{"-0", 0},
+		{"0s", 0},
+		{"1ns", time.Nanosecond},
+		{"1us", time.Microsecond},
+		{"1µs", time.Microsecond},
+		{"1μs", time.Microsecond},
+		{"1ms", time.Millisecond},
+		{"1s", time.Second},
+		{"1m", time.Minute},
+		{"1h", time.Hour},
+		{"1d", Day},
+		{"1w", Week},
+		{"1w2d", Week + 2*Day},
+		{"1d12h", 36 * time.Hour},
+		{"1.5d", 36 * time.Hour},
+		{"0.5d12h", Day},
+		{".5h", 30 * time.Minute},
+		{"1.h", time.Hour},
+		{"2h45m30.5s", 2*time.Hour + 45*time.Minute + 30*time.Second + 500*time.Millisecond},
+		{"-3h", -3 * time.Hour},
+		{"+3h", 3 * time.Hour},
+		{"-1w1ns", -(Week + time.Nanosecond)},
+		{"1.000000001s", time.Second + time.Nanosecond},
+		{"0.1ns", 0},
+		{"9223372036854775807ns", math.MaxInt64},
+		{"-9223372036854775808ns", math.MinInt64},
+		{"15250w1d23h47m16.854775807s", math.MaxInt64},
+	}
+	for _, tt := range tests {
+		got, err := ParseDuration(tt.in)
+		if err != nil {
+			t.Errorf("ParseDuration(%q) error: %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseDuration(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseDurationErrors(t *testing.T) {
+	for _, in := range []string{
+		"",
+		"-",
+		"+",
+		"d",
+		".d",
+		"1",
+		"10",
+		"1x",
+		"1dd",
+		"1 d",
+		"1d-2h",
+		"--1h",
+		"1..5h",
+		"9223372036854775808ns",
+		"-9223372036854775809ns",
+		"15251w",
+		"106751991167301d",
+		"99999999999999999999h",
+	} {
+		if got, err := ParseDuration(in); err == nil {
+			t.Errorf("ParseDuration(%q) = %v, want error", in, got)
+		}
+	}
+}
+
+// 与time.ParseDuration支持的输入保持一致
+func TestParseDurationMatchesStdlib(t *testing.T) {
+	for _, in := range []string{"1h", "1h30m", "-1.5h", "300ms", "2h45m", "1.5us", "0.000001s", "72h3m0.5s"} {
+		want, err := time.ParseDuration(in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, err := ParseDuration(in); err != nil || got != want {
+			t.Errorf("ParseDuration(%q) = %v, %v; time.ParseDuration = %v", in, got, err, want)
+		}
+	}
+}
+
+func TestFormatDurationShort(t *testing.T) {
+	tests := []struct {
+		in   time.Duration
+		want string
+	}{
+		{0, "0s"},
+		{time.Nanosecond, "1ns"},
+		{90 * time.Second, "1m30s"},
+		{Week + 2*Day + 3*time.Hour, "1w2d3h"},
+		{-36 * time.Hour, "-1d12h"},
+		{time.Second + 500*time.Millisecond, "1s500ms"},
+		{math.MaxInt64, "15250w1d23h47m16s854ms775us807ns"},
+		{math.MinInt64, "-15250w1d23h47m16s854ms775us808ns"},
+	}
+	for _, tt := range tests {
+		if got := FormatDurationShort(tt.in); got != tt.want {
+			t.Errorf("FormatDurationShort(%v) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+// FormatDurationShort的输出必须能被ParseDuration解析回原值
+func TestDurationRoundTrip(t *testing.T) {
+	values := []time.Duration{0, 1, -1, time.Microsecond, Day, Week, Week - 1, math.MaxInt64, math.MinInt64, math.MinInt64 + 1}
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 10000; i++ {
+		values = append(values, time.Duration(rng.Int63()), -time.Duration(rng.Int63()),
+			time.Duration(rng.Int63n(int64(30*Day))))
+	}
+
+	for _, d := range values {
+		formatted := FormatDurationShort(d)
+		parsed, err := ParseDuration(formatted)
+		if err != nil {
+			t.Fatalf("ParseDuration(FormatDurationShort(%d) = %q) error: %v", int64(d), formatted, err)
+		}
+		if parsed != d {
+			t.Fatalf("round trip of %d via %q gave %d", int64(d), formatted, int64(parsed))
+		}
+	}
+}